@@ -11,10 +11,12 @@ package main
 import (
 	"flag"
 	"fmt"
+	"foglute/pkg/config"
 	"foglute/pkg/deployment"
 	"foglute/pkg/edgeusher"
 	"foglute/pkg/infrastructure"
 	"foglute/pkg/interface"
+	"k8s.io/apimachinery/pkg/labels"
 	"log"
 	"math/rand"
 	"os"
@@ -38,6 +40,30 @@ func main() {
 	}
 
 	edgeUsherPath := flag.String("edgeusher", "", "absolute path to EdgeUsher folder")
+	securityMappingsPath := flag.String("security-mappings", "", "path of the JSON file that maps security capabilities to Kubernetes settings")
+
+	cfg := config.NewDefaultConfig()
+	flag.Var(&cfg.ResourceWeights, "hw-weights", "HW units per resource unit (cpu per core, memory and ephemeral-storage per GiB, extended resources per unit)")
+	flag.BoolVar(&cfg.ForceRelocation, "force-relocation", false, "allow services with persistent volumes to move away from their data")
+	flag.StringVar(&cfg.IngressNodeSelector, "ingress-node-selector", "", "label selector of the nodes that run the ingress controller")
+	flag.StringVar(&cfg.IngressClass, "ingress-class", "", "ingress class of the Ingresses of applications")
+	flag.BoolVar(&cfg.SecurityPolicy.AllowPrivileged, "allow-privileged", cfg.SecurityPolicy.AllowPrivileged, "allow privileged containers")
+	flag.Var(&cfg.SecurityPolicy.AllowedCapabilities, "allowed-capabilities", "capabilities that containers can add (e.g. NET_ADMIN,SYS_TIME)")
+	flag.Var(&cfg.SecurityPolicy.AllowedDevices, "allowed-devices", "node devices that containers can access (e.g. /dev/ttyUSB*,/dev/i2c-1)")
+	flag.Var(&cfg.SecurityPolicy.AllowedHostPaths, "allowed-host-paths", "node directories that host_path volumes can mount read-only, or writable with :rw (e.g. /var/log,/data:rw)")
+	flag.BoolVar(&cfg.SecurityPolicy.AllowUnconfined, "allow-unconfined-seccomp", false, "allow containers to disable seccomp filtering")
+	flag.BoolVar(&cfg.BandwidthShaping, "bandwidth-shaping", false, "limit the traffic of pods to the bandwidth of their flows (requires the CNI bandwidth plugin)")
+	flag.BoolVar(&cfg.FlowPolicies, "flow-policies", false, "generate NetworkPolicies that allow only the declared flows of every application")
+	flag.BoolVar(&cfg.SecurityPolicy.RequireNonRoot, "require-non-root", false, "run containers that are not privileged as non-root users")
+	flag.Var(&cfg.HWUnit, "hw-unit", "resources requested by containers for each unit of hw_reqs (e.g. cpu=500m,memory=512Mi)")
+	flag.StringVar(&cfg.Namespace, "namespace", cfg.Namespace, "namespace of the applications that do not declare one")
+	flag.StringVar(&cfg.FailurePolicy, "on-failure", cfg.FailurePolicy, "objects of a failed deploy are removed (rollback) or left on the cluster (keep)")
+	flag.DurationVar(&cfg.RolloutTimeout, "rollout-timeout", cfg.RolloutTimeout, "maximum time to wait for services to be ready (0 to disable)")
+	flag.IntVar(&cfg.MaxReplacements, "max-replacements", cfg.MaxReplacements, "times services that fail on their node are placed again")
+	flag.Var(&cfg.Contexts, "contexts", "kubeconfig contexts of the clusters managed by FogLute (e.g. site-a,site-b)")
+	flag.IntVar(&cfg.InterClusterLatency, "inter-cluster-latency", cfg.InterClusterLatency, "latency in ms between nodes of different clusters")
+	flag.IntVar(&cfg.InterClusterBandwidth, "inter-cluster-bandwidth", cfg.InterClusterBandwidth, "bandwidth in Mbps between nodes of different clusters")
+	flag.StringVar(&cfg.NodeSelector, "node-selector", "", "label selector of the nodes managed by FogLute (e.g. node-role/edge=true)")
 
 	flag.Parse()
 
@@ -46,11 +72,31 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *securityMappingsPath != "" {
+		mappings, err := config.LoadSecurityMappings(*securityMappingsPath)
+		if err != nil {
+			fmt.Printf("Invalid security mappings: %s\n", err)
+			os.Exit(1)
+		}
+
+		cfg.SecurityMappings = mappings
+	}
+
+	if cfg.FailurePolicy != config.RollbackOnFailure && cfg.FailurePolicy != config.KeepOnFailure {
+		fmt.Printf("Invalid failure policy: %s\n", cfg.FailurePolicy)
+		os.Exit(1)
+	}
+
+	if _, err := labels.Parse(cfg.NodeSelector); err != nil {
+		fmt.Printf("Invalid node selector %s: %s\n", cfg.NodeSelector, err)
+		os.Exit(1)
+	}
+
 	stopChan := make(chan os.Signal, 1)
 	quit := make(chan struct{}, 1)
 	signal.Notify(stopChan, syscall.SIGINT, syscall.SIGTERM)
 
-	clientset, err := infrastructure.GetClientSet(*kubeconfig)
+	clusters, err := infrastructure.GetClusters(*kubeconfig, cfg.Contexts)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -61,7 +107,7 @@ func main() {
 		log.Fatal(err)
 	}
 
-	manager, err := deployment.NewDeploymentManager(&analyzer, clientset, quit)
+	manager, err := deployment.NewDeploymentManager(&analyzer, clusters, cfg, quit)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,141 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package model
+
+import "fmt"
+
+const (
+	// The services must be placed on the same node
+	CoLocatedAffinity = "co_located"
+
+	// The services must be placed on distinct nodes
+	NotCoLocatedAffinity = "not_co_located"
+
+	// The service must be placed on the node of the target service
+	SameNodeAffinity = "same_node_as"
+)
+
+// An AffinityRule constrains services of an application to share a node or to avoid each other.
+// co_located and not_co_located rules apply to Services, same_node_as rules bind Service to Target.
+type AffinityRule struct {
+	Type     string   `json:"type"`
+	Services []string `json:"services"`
+	Service  string   `json:"service"`
+	Target   string   `json:"target"`
+}
+
+// Returns the services constrained by the rule
+func (r AffinityRule) GetServices() []string {
+	if r.Type == SameNodeAffinity {
+		return []string{r.Service, r.Target}
+	}
+
+	return r.Services
+}
+
+// Returns true if the services of the rule must share a node
+func (r AffinityRule) IsCoLocation() bool {
+	return r.Type == CoLocatedAffinity || r.Type == SameNodeAffinity
+}
+
+// Checks that the placement satisfies the affinity rules of the application
+func (a *Application) CheckAffinities(placement *Placement) error {
+	nodes := make(map[string]string)
+	for _, assignment := range placement.Assignments {
+		nodes[assignment.ServiceID] = assignment.NodeName
+	}
+
+	for _, r := range a.Affinities {
+		services := r.GetServices()
+
+		for i := 0; i < len(services); i++ {
+			for j := i + 1; j < len(services); j++ {
+				n1, placed1 := nodes[services[i]]
+				n2, placed2 := nodes[services[j]]
+				if !placed1 || !placed2 {
+					continue
+				}
+
+				if r.IsCoLocation() && n1 != n2 {
+					return fmt.Errorf("services %s and %s must be on the same node", services[i], services[j])
+				}
+
+				if !r.IsCoLocation() && n1 == n2 {
+					return fmt.Errorf("services %s and %s cannot be on the same node", services[i], services[j])
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Checks that the affinity rules are well formed and not contradictory
+func (a *Application) validateAffinities() error {
+	// Groups of services that share a node
+	group := make(map[string]string)
+	var find func(id string) string
+	find = func(id string) string {
+		if g, exists := group[id]; exists && g != id {
+			return find(g)
+		}
+
+		return id
+	}
+
+	for _, r := range a.Affinities {
+		switch r.Type {
+		case CoLocatedAffinity, NotCoLocatedAffinity:
+			if len(r.Services) < 2 {
+				return fmt.Errorf("%s rule requires at least two services", r.Type)
+			}
+		case SameNodeAffinity:
+			if r.Service == "" || r.Target == "" {
+				return fmt.Errorf("%s rule requires a service and a target", r.Type)
+			}
+		default:
+			return fmt.Errorf("unknown affinity rule %s", r.Type)
+		}
+
+		seen := make(map[string]bool)
+		for _, id := range r.GetServices() {
+			if _, exists := a.GetService(id); !exists {
+				return fmt.Errorf("%s rule: unknown service %s", r.Type, id)
+			}
+
+			if seen[id] {
+				return fmt.Errorf("%s rule: duplicated service %s", r.Type, id)
+			}
+			seen[id] = true
+		}
+
+		if r.IsCoLocation() {
+			services := r.GetServices()
+			for _, id := range services[1:] {
+				group[find(id)] = find(services[0])
+			}
+		}
+	}
+
+	for _, r := range a.Affinities {
+		if r.IsCoLocation() {
+			continue
+		}
+
+		for i, s1 := range r.Services {
+			for _, s2 := range r.Services[i+1:] {
+				if find(s1) == find(s2) {
+					return fmt.Errorf("services %s and %s must be both on the same node and on distinct nodes", s1, s2)
+				}
+			}
+		}
+	}
+
+	return nil
+}
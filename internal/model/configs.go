@@ -0,0 +1,185 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package model
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"path"
+	"strings"
+)
+
+const (
+	// Value that replaces secret data in the application descriptors exposed by FogLute
+	RedactedValue = "<redacted>"
+)
+
+// A ConfigSource declares configuration data of an application, stored in a ConfigMap or in a Secret.
+// FogLute creates the object with the given Data, unless Existing names an object already present in the
+// namespace of the application.
+type ConfigSource struct {
+	Name     string            `json:"name"`
+	Data     map[string]string `json:"data"`
+	Existing string            `json:"existing"`
+}
+
+// A ConfigMount mounts each key of a config or of a secret as a read-only file in a directory of the container
+type ConfigMount struct {
+	Config string `json:"config"`
+	Secret string `json:"secret"`
+	Path   string `json:"path"`
+}
+
+// An EnvReference sets environment variables of the container from a config or a secret.
+// With a key, the variable Name gets its value; without a key, every key of the source becomes a variable.
+type EnvReference struct {
+	Name   string `json:"name"`
+	Config string `json:"config"`
+	Secret string `json:"secret"`
+	Key    string `json:"key"`
+}
+
+// Returns the config with the given name
+func (a *Application) GetConfig(name string) (*ConfigSource, bool) {
+	for i := range a.Configs {
+		if a.Configs[i].Name == name {
+			return &a.Configs[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// Returns the secret with the given name
+func (a *Application) GetSecret(name string) (*ConfigSource, bool) {
+	for i := range a.Secrets {
+		if a.Secrets[i].Name == name {
+			return &a.Secrets[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// Returns a copy of the application in which the values of the secrets and the registry passwords are redacted
+func (a *Application) Redacted() *Application {
+	redacted := *a
+	redacted.Secrets = make([]ConfigSource, len(a.Secrets))
+
+	for i, s := range a.Secrets {
+		if s.Data != nil {
+			data := make(map[string]string, len(s.Data))
+			for key := range s.Data {
+				data[key] = RedactedValue
+			}
+			s.Data = data
+		}
+
+		redacted.Secrets[i] = s
+	}
+
+	redacted.Registries = make([]RegistryCredential, len(a.Registries))
+	for i, r := range a.Registries {
+		if r.Password != "" {
+			r.Password = RedactedValue
+		}
+
+		redacted.Registries[i] = r
+	}
+
+	return &redacted
+}
+
+// Checks that the configs and the secrets of the application are well formed
+func (a *Application) validateConfigs() error {
+	for kind, sources := range map[string][]ConfigSource{"config": a.Configs, "secret": a.Secrets} {
+		names := make(map[string]bool)
+
+		for _, s := range sources {
+			if s.Name == "" {
+				return fmt.Errorf("a %s of application %s has no name", kind, a.ID)
+			}
+
+			if names[s.Name] {
+				return fmt.Errorf("duplicated %s %s", kind, s.Name)
+			}
+			names[s.Name] = true
+
+			if s.Existing != "" {
+				if len(s.Data) > 0 {
+					return fmt.Errorf("%s %s: cannot have both data and an existing object", kind, s.Name)
+				}
+
+				if errs := validation.IsDNS1123Subdomain(s.Existing); len(errs) > 0 {
+					return fmt.Errorf("%s %s: invalid existing object %s: %s", kind, s.Name, s.Existing, strings.Join(errs, ", "))
+				}
+			}
+
+			for key := range s.Data {
+				if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+					return fmt.Errorf("%s %s: invalid key %s: %s", kind, s.Name, key, strings.Join(errs, ", "))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Checks that a reference to a config or a secret is well formed
+func (a *Application) validateReference(service *Service, image *Image, config string, secret string) error {
+	if (config == "") == (secret == "") {
+		return fmt.Errorf("service %s, image %s: exactly one of config and secret must be set", service.Id, image.Name)
+	}
+
+	if _, exists := a.GetConfig(config); config != "" && !exists {
+		return fmt.Errorf("service %s, image %s: unknown config %s", service.Id, image.Name, config)
+	}
+
+	if _, exists := a.GetSecret(secret); secret != "" && !exists {
+		return fmt.Errorf("service %s, image %s: unknown secret %s", service.Id, image.Name, secret)
+	}
+
+	return nil
+}
+
+// Checks that the mounts and the environment references of an image are well formed
+func (a *Application) validateConfigUsage(service *Service, image *Image) error {
+	paths := make(map[string]bool)
+	for _, m := range image.Mounts {
+		if err := a.validateReference(service, image, m.Config, m.Secret); err != nil {
+			return err
+		}
+
+		if !path.IsAbs(m.Path) {
+			return fmt.Errorf("service %s, image %s: mount path %s is not absolute", service.Id, image.Name, m.Path)
+		}
+
+		if paths[path.Clean(m.Path)] {
+			return fmt.Errorf("service %s, image %s: duplicated mount path %s", service.Id, image.Name, m.Path)
+		}
+		paths[path.Clean(m.Path)] = true
+	}
+
+	for _, e := range image.EnvFrom {
+		if err := a.validateReference(service, image, e.Config, e.Secret); err != nil {
+			return err
+		}
+
+		if e.Key != "" && e.Name == "" {
+			return fmt.Errorf("service %s, image %s: variable for key %s has no name", service.Id, image.Name, e.Key)
+		}
+
+		if e.Key == "" && e.Name != "" {
+			return fmt.Errorf("service %s, image %s: variable %s has no key", service.Id, image.Name, e.Name)
+		}
+	}
+
+	return nil
+}
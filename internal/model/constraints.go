@@ -0,0 +1,43 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package model
+
+// Returns true if the service can be placed on the node according to its constraints
+func (s *Service) CanRunOn(node *Node) bool {
+	if !s.ToleratesTaintsOf(node) {
+		return false
+	}
+
+	if s.NodeName != "" && s.NodeName != node.Name {
+		return false
+	}
+
+	if !s.HasStorageOn(node) {
+		return false
+	}
+
+	for _, excluded := range s.ExcludedNodes {
+		if excluded == node.Name {
+			return false
+		}
+	}
+
+	// Replicas spread over zones can only run in a zone
+	if s.ReplicaOf != "" && s.GetSpread() == SpreadZones && node.Location.Zone == "" {
+		return false
+	}
+
+	for _, c := range s.LocationConstraints {
+		if !c.Admits(node) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,90 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package model
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"testing"
+)
+
+func TestCanRunOn(t *testing.T) {
+	// Bologna and Pisa are about 120 km apart
+	bologna := Location{Latitude: 44, Longitude: 11, Zone: "emilia"}
+	pisa := Location{Latitude: 43, Longitude: 10, Zone: "tuscany"}
+
+	edgeTaint := v1.Taint{Key: "edge", Value: "true", Effect: v1.TaintEffectNoSchedule}
+	gpuTaint := v1.Taint{Key: "gpu", Effect: v1.TaintEffectNoExecute}
+
+	node := Node{Name: "node-1", Location: bologna}
+
+	tests := []struct {
+		name    string
+		service Service
+		node    Node
+		want    bool
+	}{
+		{"no constraints", Service{}, node, true},
+
+		{"pinned to the node", Service{NodeName: "node-1"}, node, true},
+		{"pinned to another node", Service{NodeName: "node-2"}, node, false},
+
+		{"excluded node", Service{ExcludedNodes: []string{"node-2", "node-1"}}, node, false},
+		{"other excluded nodes", Service{ExcludedNodes: []string{"node-2"}}, node, true},
+
+		{"untolerated taint", Service{}, Node{Name: "node-1", Taints: []v1.Taint{edgeTaint}}, false},
+		{"tolerated taint", Service{Tolerations: []Toleration{{Key: "edge", Value: "true", Effect: "NoSchedule"}}},
+			Node{Name: "node-1", Taints: []v1.Taint{edgeTaint}}, true},
+		{"toleration with another value", Service{Tolerations: []Toleration{{Key: "edge", Value: "false", Effect: "NoSchedule"}}},
+			Node{Name: "node-1", Taints: []v1.Taint{edgeTaint}}, false},
+		{"toleration with another effect", Service{Tolerations: []Toleration{{Key: "edge", Value: "true", Effect: "NoExecute"}}},
+			Node{Name: "node-1", Taints: []v1.Taint{edgeTaint}}, false},
+		{"exists toleration", Service{Tolerations: []Toleration{{Key: "edge", Operator: "Exists"}}},
+			Node{Name: "node-1", Taints: []v1.Taint{edgeTaint}}, true},
+		{"one of many taints tolerated", Service{Tolerations: []Toleration{{Key: "edge", Operator: "Exists"}}},
+			Node{Name: "node-1", Taints: []v1.Taint{edgeTaint, gpuTaint}}, false},
+		{"all taints tolerated", Service{Tolerations: []Toleration{{Key: "edge", Operator: "Exists"}, {Key: "gpu", Operator: "Exists"}}},
+			Node{Name: "node-1", Taints: []v1.Taint{edgeTaint, gpuTaint}}, true},
+
+		{"provided storage class", Service{Volumes: []Volume{{Name: "data", Type: PersistentVolume, StorageClass: "local-path"}}},
+			Node{Name: "node-1", StorageClasses: []string{"nfs", "local-path"}}, true},
+		{"missing storage class", Service{Volumes: []Volume{{Name: "data", Type: PersistentVolume, StorageClass: "local-path"}}},
+			Node{Name: "node-1", StorageClasses: []string{"nfs"}}, false},
+		{"default storage class", Service{Volumes: []Volume{{Name: "data", Type: PersistentVolume}}}, node, true},
+		{"storage class of a host path", Service{Volumes: []Volume{{Name: "logs", Type: HostPathVolume, StorageClass: "local-path"}}}, node, true},
+
+		{"replica spread over zones", Service{ReplicaOf: "db", Spread: SpreadZones}, node, true},
+		{"replica spread over zones on a node without zone", Service{ReplicaOf: "db", Spread: SpreadZones}, Node{Name: "node-1"}, false},
+		{"replica spread over nodes on a node without zone", Service{ReplicaOf: "db"}, Node{Name: "node-1"}, true},
+
+		{"within radius", Service{LocationConstraints: []LocationConstraint{{Type: WithinRadiusConstraint, Center: pisa, Radius: 200}}}, node, true},
+		{"outside radius", Service{LocationConstraints: []LocationConstraint{{Type: WithinRadiusConstraint, Center: pisa, Radius: 50}}}, node, false},
+		{"in zone", Service{LocationConstraints: []LocationConstraint{{Type: InZoneConstraint, Zone: "emilia"}}}, node, true},
+		{"in another zone", Service{LocationConstraints: []LocationConstraint{{Type: InZoneConstraint, Zone: "tuscany"}}}, node, false},
+		{"in zone on a node without zone", Service{LocationConstraints: []LocationConstraint{{Type: InZoneConstraint, Zone: "emilia"}}}, Node{Name: "node-1"}, false},
+		{"same zone as", Service{LocationConstraints: []LocationConstraint{{Type: SameZoneConstraint, Service: "db"}}}, node, true},
+		{"same zone as on a node without zone", Service{LocationConstraints: []LocationConstraint{{Type: SameZoneConstraint, Service: "db"}}}, Node{Name: "node-1"}, false},
+		{"all constraints satisfied", Service{LocationConstraints: []LocationConstraint{
+			{Type: WithinRadiusConstraint, Center: pisa, Radius: 200},
+			{Type: InZoneConstraint, Zone: "emilia"},
+		}}, node, true},
+		{"one constraint violated", Service{LocationConstraints: []LocationConstraint{
+			{Type: WithinRadiusConstraint, Center: pisa, Radius: 200},
+			{Type: InZoneConstraint, Zone: "tuscany"},
+		}}, node, false},
+		{"unknown constraint", Service{LocationConstraints: []LocationConstraint{{Type: "near"}}}, node, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.service.CanRunOn(&tt.node); got != tt.want {
+				t.Errorf("CanRunOn() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
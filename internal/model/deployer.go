@@ -23,9 +23,31 @@ const (
 type Application struct {
 	ID           string                  `json:"id"`
 	Name         string                  `json:"name"`
+	Namespace    string                  `json:"namespace"`
 	Services     []Service               `json:"services"`
 	Flows        []Flow                  `json:"flows"`
 	MaxLatencies []MaxLatencyDescription `json:"max_latency"`
+
+	// Labels that nodes must have to host the services of the application
+	NodeSelector map[string]string `json:"node_selector"`
+
+	// Configuration data and credentials used by the services
+	Configs []ConfigSource `json:"configs"`
+	Secrets []ConfigSource `json:"secrets"`
+
+	// Rules on the services that must or must not share a node
+	Affinities []AffinityRule `json:"affinities"`
+
+	// HTTP routes from outside the cluster to the services
+	Routes []Route `json:"routes"`
+
+	// Credentials of private registries, and pull settings of the images that do not set their own
+	Registries []RegistryCredential `json:"registries"`
+	PullPolicy string               `json:"pull_policy"`
+	PullSecret string               `json:"pull_secret"`
+
+	// Restrict the traffic of the services to the declared flows
+	FlowPolicies bool `json:"flow_policies"`
 }
 
 // A Service is a part of an application that can be executed.
@@ -37,6 +59,27 @@ type Service struct {
 	SecReqs  []string `json:"sec_reqs"`
 	Images   []Image  `json:"images"`
 	NodeName string   `json:"node_name"`
+
+	LocationConstraints []LocationConstraint `json:"location_constraints"`
+
+	// Structured hardware requirements. If set, they replace HWReqs in the analysis
+	Resources *Resources `json:"resources"`
+
+	// Taints of the nodes that the service tolerates
+	Tolerations []Toleration `json:"tolerations"`
+
+	// Storage attached to the containers of the service
+	Volumes []Volume `json:"volumes"`
+
+	// Number of instances of the service, and how they are spread over the nodes
+	Replicas int    `json:"replicas"`
+	Spread   string `json:"spread"`
+
+	// Service of which this one is a replica in the analysis
+	ReplicaOf string `json:"-"`
+
+	// Nodes on which the service failed to start, excluded when the service is placed again
+	ExcludedNodes []string `json:"-"`
 }
 
 // An Image is a description of a Docker image to be used by a Service
@@ -46,6 +89,33 @@ type Image struct {
 	Env        map[string]string `json:"env"`
 	Ports      []Port            `json:"ports"`
 	Privileged bool              `json:"privileged"`
+
+	// Resources of the container. If not set, the container gets a share of the service resources
+	Resources *ResourceRequirements `json:"resources"`
+
+	// Entrypoint and arguments overriding the ones of the image
+	Command    []string `json:"command"`
+	Args       []string `json:"args"`
+	WorkingDir string   `json:"working_dir"`
+
+	// Health checks of the container
+	LivenessProbe  *Probe `json:"liveness_probe"`
+	ReadinessProbe *Probe `json:"readiness_probe"`
+
+	// Configs and secrets mounted as files or injected as environment variables
+	Mounts  []ConfigMount  `json:"mounts"`
+	EnvFrom []EnvReference `json:"env_from"`
+
+	// Volumes of the service mounted in the container
+	VolumeMounts []VolumeMount `json:"volume_mounts"`
+
+	// Pull policy of the image and registry credential used to pull it
+	PullPolicy string `json:"pull_policy"`
+	PullSecret string `json:"pull_secret"`
+
+	// Privileges of the container and devices of the node it can access
+	Security *SecurityContext `json:"security"`
+	Devices  []Device         `json:"devices"`
 }
 
 type Port struct {
@@ -53,6 +123,18 @@ type Port struct {
 	HostPort      int    `json:"host_port"`
 	ContainerPort int    `json:"container_port"`
 	Expose        int    `json:"expose"`
+
+	// TCP, UDP or SCTP
+	Protocol string `json:"protocol"`
+
+	// Type of the Kubernetes Service that exposes the port
+	ServiceType string `json:"service_type"`
+
+	// Ports with the same service are exposed by the same Kubernetes Service. It defaults to the port name
+	Service string `json:"service"`
+
+	// Port of the Kubernetes Service
+	ServicePort int `json:"service_port"`
 }
 
 // A Flow is a requirement that a connection between two services must satisfy
@@ -60,6 +142,12 @@ type Flow struct {
 	Src       string `json:"src"`
 	Dst       string `json:"dst"`
 	Bandwidth int    `json:"bandwidth"`
+
+	// Prefix of the environment variables that address Dst in the containers of Src. It defaults to the id of Dst
+	Env string `json:"env"`
+
+	// Src reaches the replica of Dst placed on its node, if any
+	PreferLocal bool `json:"prefer_local"`
 }
 
 // A MaxLatencyDescription describe the maximum latency that a chain of services should have
@@ -87,6 +175,15 @@ type Node struct {
 	Location Location      `json:"location"`
 	Profiles []NodeProfile `json:"profiles"`
 
+	// NoSchedule and NoExecute taints of the node
+	Taints []v1.Taint `json:"taints"`
+
+	// Storage classes that can provision volumes on the node
+	StorageClasses []string `json:"storage_classes"`
+
+	// Kubernetes cluster of the node, if FogLute manages many clusters
+	Cluster string `json:"cluster,omitempty"`
+
 	Node *v1.Node `json:"-"`
 }
 
@@ -97,16 +194,18 @@ func (n Node) String() string {
 
 // A Location represent a geo-located place in the world
 type Location struct {
-	Longitude int `json:"longitude"`
-	Latitude  int `json:"latitude"`
+	Longitude int    `json:"longitude"`
+	Latitude  int    `json:"latitude"`
+	Zone      string `json:"zone"`
 }
 
 // A NodeProfile describes the capabilities of a node taking in consideration the probability of that configuration
 type NodeProfile struct {
-	Probability float64  `json:"probability"`
-	HWCaps      int64    `json:"hw_caps"`
-	IoTCaps     []string `json:"iot_caps"`
-	SecCaps     []string `json:"sec_caps"`
+	Probability float64   `json:"probability"`
+	HWCaps      int64     `json:"hw_caps"`
+	IoTCaps     []string  `json:"iot_caps"`
+	SecCaps     []string  `json:"sec_caps"`
+	Resources   Resources `json:"resources"`
 }
 
 // A Link is a connection between two nodes
@@ -134,4 +233,10 @@ type Assignment struct {
 	ServiceID string `json:"service_id"`
 	NodeID    string `json:"node_id"`
 	NodeName  string `json:"node_name"`
+
+	// Index of the replica of the service
+	Replica int `json:"replica"`
+
+	// Kubernetes cluster of the node, if FogLute manages many clusters
+	Cluster string `json:"cluster,omitempty"`
 }
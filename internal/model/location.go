@@ -0,0 +1,93 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package model
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// The Service must be placed within a radius (in km) from a point
+	WithinRadiusConstraint = "within_radius"
+
+	// The Service must be placed on a node inside a named zone
+	InZoneConstraint = "in_zone"
+
+	// The Service must be placed in the same zone of another Service of the application
+	SameZoneConstraint = "same_zone_as"
+
+	// Mean radius of the Earth in km
+	earthRadius = 6371.0
+)
+
+// A LocationConstraint restricts the nodes a Service can be placed on according to their Location
+type LocationConstraint struct {
+	Type    string   `json:"type"`
+	Center  Location `json:"center"`
+	Radius  int      `json:"radius"`
+	Zone    string   `json:"zone"`
+	Service string   `json:"service"`
+}
+
+// Returns the great-circle distance in km between two locations
+func (l Location) DistanceTo(other Location) float64 {
+	lat1 := toRadians(float64(l.Latitude))
+	lat2 := toRadians(float64(other.Latitude))
+	dLat := lat2 - lat1
+	dLong := toRadians(float64(other.Longitude - l.Longitude))
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLong/2)*math.Sin(dLong/2)
+
+	return earthRadius * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// Returns true if the node satisfies the constraint.
+// A same_zone_as constraint depends on the placement of another service, so any node inside a zone satisfies it.
+func (c LocationConstraint) Admits(node *Node) bool {
+	switch c.Type {
+	case WithinRadiusConstraint:
+		return node.Location.DistanceTo(c.Center) <= float64(c.Radius)
+	case InZoneConstraint:
+		return node.Location.Zone != "" && node.Location.Zone == c.Zone
+	case SameZoneConstraint:
+		return node.Location.Zone != ""
+	}
+
+	return false
+}
+
+// Checks that the constraint is well formed
+func (c LocationConstraint) validate(application *Application, service *Service) error {
+	switch c.Type {
+	case WithinRadiusConstraint:
+		if c.Radius <= 0 {
+			return fmt.Errorf("service %s: radius must be positive", service.Id)
+		}
+	case InZoneConstraint:
+		if c.Zone == "" {
+			return fmt.Errorf("service %s: missing zone", service.Id)
+		}
+	case SameZoneConstraint:
+		if c.Service == service.Id {
+			return fmt.Errorf("service %s: cannot be in the same zone of itself", service.Id)
+		}
+		if _, exists := application.GetService(c.Service); !exists {
+			return fmt.Errorf("service %s: unknown service %s", service.Id, c.Service)
+		}
+	default:
+		return fmt.Errorf("service %s: unknown location constraint %s", service.Id, c.Type)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// The port is reachable inside the cluster on a virtual IP
+	ClusterIPService = "cluster_ip"
+
+	// The port is also reachable on a port of every node
+	NodePortService = "node_port"
+
+	// The port is also reachable through an external load balancer
+	LoadBalancerService = "load_balancer"
+
+	// The port is reachable on the addresses of the pods, resolved through DNS
+	HeadlessService = "headless"
+)
+
+// Returns the protocol of the port. Ports use TCP by default
+func (p Port) GetProtocol() string {
+	if p.Protocol == "" {
+		return "TCP"
+	}
+
+	return strings.ToUpper(p.Protocol)
+}
+
+// Returns the type of the Kubernetes Service that exposes the port, or an empty string if the port is not exposed.
+// Ports with an expose port and no service type are exposed by a load balancer.
+func (p Port) GetServiceType() string {
+	if p.ServiceType == "" && p.Expose > 0 {
+		return LoadBalancerService
+	}
+
+	return p.ServiceType
+}
+
+// Returns true if the port is exposed by a Kubernetes Service
+func (p Port) IsExposed() bool {
+	return p.GetServiceType() != ""
+}
+
+// Returns the name of the group of ports exposed by the same Kubernetes Service
+func (p Port) GetServiceName() string {
+	if p.Service != "" {
+		return p.Service
+	}
+
+	return p.Name
+}
+
+// Returns the port of the Kubernetes Service. It defaults to the host port, or to the container port
+func (p Port) GetServicePort() int {
+	if p.ServicePort > 0 {
+		return p.ServicePort
+	}
+
+	if p.HostPort > 0 {
+		return p.HostPort
+	}
+
+	return p.ContainerPort
+}
+
+// Checks that the ports of the service are well formed and that the ports exposed by the same Kubernetes Service agree
+func (s *Service) validatePorts() error {
+	isValidPort := func(port int) bool {
+		return port > 0 && port <= 65535
+	}
+
+	serviceTypes := make(map[string]string)
+	servicePorts := make(map[string]bool)
+
+	for _, image := range s.Images {
+		containerPorts := make(map[string]bool)
+
+		for _, p := range image.Ports {
+			if !isValidPort(p.ContainerPort) {
+				return fmt.Errorf("service %s, image %s: invalid container port %d", s.Id, image.Name, p.ContainerPort)
+			}
+
+			if p.HostPort != 0 && !isValidPort(p.HostPort) {
+				return fmt.Errorf("service %s, image %s: invalid host port %d", s.Id, image.Name, p.HostPort)
+			}
+
+			switch p.GetProtocol() {
+			case "TCP", "UDP", "SCTP":
+			default:
+				return fmt.Errorf("service %s, image %s: unknown protocol %s", s.Id, image.Name, p.Protocol)
+			}
+
+			key := fmt.Sprintf("%d/%s", p.ContainerPort, p.GetProtocol())
+			if containerPorts[key] {
+				return fmt.Errorf("service %s, image %s: duplicated container port %s", s.Id, image.Name, key)
+			}
+			containerPorts[key] = true
+
+			if !p.IsExposed() {
+				continue
+			}
+
+			switch p.GetServiceType() {
+			case ClusterIPService, HeadlessService:
+				if p.Expose != 0 {
+					return fmt.Errorf("service %s, image %s: port %d cannot be exposed on nodes by a %s service", s.Id, image.Name, p.ContainerPort, p.GetServiceType())
+				}
+			case NodePortService, LoadBalancerService:
+				if p.Expose != 0 && !isValidPort(p.Expose) {
+					return fmt.Errorf("service %s, image %s: invalid expose port %d", s.Id, image.Name, p.Expose)
+				}
+			default:
+				return fmt.Errorf("service %s, image %s: unknown service type %s", s.Id, image.Name, p.ServiceType)
+			}
+
+			if p.ServicePort != 0 && !isValidPort(p.ServicePort) {
+				return fmt.Errorf("service %s, image %s: invalid service port %d", s.Id, image.Name, p.ServicePort)
+			}
+
+			name := p.GetServiceName()
+			if t, exists := serviceTypes[name]; exists && t != p.GetServiceType() {
+				return fmt.Errorf("service %s: ports of %s have different service types", s.Id, name)
+			}
+			serviceTypes[name] = p.GetServiceType()
+
+			key = fmt.Sprintf("%s:%d/%s", name, p.GetServicePort(), p.GetProtocol())
+			if servicePorts[key] {
+				return fmt.Errorf("service %s: duplicated port %d/%s in %s", s.Id, p.GetServicePort(), p.GetProtocol(), name)
+			}
+			servicePorts[key] = true
+		}
+	}
+
+	return nil
+}
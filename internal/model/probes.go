@@ -0,0 +1,140 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package model
+
+import (
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// The probe performs an HTTP GET request on a path of the container
+	HTTPProbe = "http"
+
+	// The probe opens a TCP connection to a port of the container
+	TCPProbe = "tcp"
+
+	// The probe runs a command inside the container
+	ExecProbe = "exec"
+)
+
+// A Probe describes a health check of a container.
+// HTTP and TCP probes without a port check the first container port of the image.
+type Probe struct {
+	Type    string   `json:"type"`
+	Path    string   `json:"path"`
+	Port    int      `json:"port"`
+	Command []string `json:"command"`
+
+	InitialDelaySeconds int32 `json:"initial_delay_seconds"`
+	PeriodSeconds       int32 `json:"period_seconds"`
+	TimeoutSeconds      int32 `json:"timeout_seconds"`
+	SuccessThreshold    int32 `json:"success_threshold"`
+	FailureThreshold    int32 `json:"failure_threshold"`
+}
+
+// Returns the port checked by the probe
+func (p Probe) getPort(image *Image) int {
+	if p.Port == 0 && len(image.Ports) > 0 {
+		return image.Ports[0].ContainerPort
+	}
+
+	return p.Port
+}
+
+// Returns the Kubernetes probe of a container of the image
+func (p Probe) ToKubernetes(image *Image) *v1.Probe {
+	probe := &v1.Probe{
+		InitialDelaySeconds: p.InitialDelaySeconds,
+		PeriodSeconds:       p.PeriodSeconds,
+		TimeoutSeconds:      p.TimeoutSeconds,
+		SuccessThreshold:    p.SuccessThreshold,
+		FailureThreshold:    p.FailureThreshold,
+	}
+
+	port := intstr.FromInt(p.getPort(image))
+
+	switch p.Type {
+	case HTTPProbe:
+		path := p.Path
+		if path == "" {
+			path = "/"
+		}
+
+		probe.HTTPGet = &v1.HTTPGetAction{
+			Path: path,
+			Port: port,
+		}
+	case TCPProbe:
+		probe.TCPSocket = &v1.TCPSocketAction{
+			Port: port,
+		}
+	case ExecProbe:
+		probe.Exec = &v1.ExecAction{
+			Command: p.Command,
+		}
+	}
+
+	return probe
+}
+
+// Checks that the probe is well formed
+func (p Probe) validate(service *Service, image *Image, name string) error {
+	switch p.Type {
+	case HTTPProbe, TCPProbe:
+		if port := p.getPort(image); port <= 0 || port > 65535 {
+			return fmt.Errorf("service %s, image %s: %s probe requires a valid port", service.Id, image.Name, name)
+		}
+	case ExecProbe:
+		if len(p.Command) == 0 {
+			return fmt.Errorf("service %s, image %s: %s probe requires a command", service.Id, image.Name, name)
+		}
+	default:
+		return fmt.Errorf("service %s, image %s: unknown %s probe type %s", service.Id, image.Name, name, p.Type)
+	}
+
+	if p.InitialDelaySeconds < 0 || p.PeriodSeconds < 0 || p.TimeoutSeconds < 0 || p.SuccessThreshold < 0 || p.FailureThreshold < 0 {
+		return fmt.Errorf("service %s, image %s: %s probe timings cannot be negative", service.Id, image.Name, name)
+	}
+
+	// Kubernetes requires liveness probes to succeed once
+	if name == "liveness" && p.SuccessThreshold > 1 {
+		return fmt.Errorf("service %s, image %s: liveness probe success threshold must be 1", service.Id, image.Name)
+	}
+
+	return nil
+}
+
+// Checks that the entrypoint and health checks of the image are well formed
+func (image *Image) validateContainer(service *Service) error {
+	if image.Name == "" {
+		return fmt.Errorf("service %s: an image has no name", service.Id)
+	}
+
+	if image.LivenessProbe != nil {
+		if err := image.LivenessProbe.validate(service, image, "liveness"); err != nil {
+			return err
+		}
+	}
+
+	if image.ReadinessProbe != nil {
+		if err := image.ReadinessProbe.validate(service, image, "readiness"); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range image.Command {
+		if c == "" {
+			return fmt.Errorf("service %s, image %s: empty command element", service.Id, image.Name)
+		}
+	}
+
+	return nil
+}
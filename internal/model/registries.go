@@ -0,0 +1,167 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package model
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"strings"
+)
+
+// Pull policies of images
+const (
+	// The image is pulled every time the container starts
+	PullAlways = "always"
+
+	// The image is pulled only if it is not on the node
+	PullIfNotPresent = "if_not_present"
+
+	// The image is never pulled and must be on the node
+	PullNever = "never"
+)
+
+// A RegistryCredential is used to pull images from a private registry.
+// It stores the credentials of the registry, or references an existing Kubernetes Secret of type dockerconfigjson.
+type RegistryCredential struct {
+	Name     string `json:"name"`
+	Server   string `json:"server"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Existing string `json:"existing"`
+}
+
+// Returns the registry credential with the given name
+func (a *Application) GetRegistry(name string) (*RegistryCredential, bool) {
+	for i := range a.Registries {
+		if a.Registries[i].Name == name {
+			return &a.Registries[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// Returns the pull policy of an image.
+// It defaults to the pull policy of the application, then to never for local images and always for the others.
+func (a *Application) GetPullPolicy(image Image) string {
+	if image.PullPolicy != "" {
+		return image.PullPolicy
+	}
+
+	if image.Local {
+		return PullNever
+	}
+
+	if a.PullPolicy != "" {
+		return a.PullPolicy
+	}
+
+	return PullAlways
+}
+
+// Returns the registry credential used to pull an image, or an empty string if there is none.
+// It defaults to the pull secret of the application.
+func (a *Application) GetPullSecret(image Image) string {
+	if image.PullSecret != "" {
+		return image.PullSecret
+	}
+
+	return a.PullSecret
+}
+
+// Checks that a pull policy is known
+func isValidPullPolicy(policy string) bool {
+	switch policy {
+	case "", PullAlways, PullIfNotPresent, PullNever:
+		return true
+	}
+
+	return false
+}
+
+// Checks that a pull secret is a registry credential of the application or a valid name of a Kubernetes Secret
+func (a *Application) validatePullSecret(secret string) error {
+	if secret == "" {
+		return nil
+	}
+
+	if _, exists := a.GetRegistry(secret); exists {
+		return nil
+	}
+
+	if errs := validation.IsDNS1123Subdomain(secret); len(errs) > 0 {
+		return fmt.Errorf("invalid pull secret %s: %s", secret, strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+// Checks that the registry credentials and the pull settings of the application are well formed
+func (a *Application) validateRegistries() error {
+	names := make(map[string]bool)
+
+	for _, r := range a.Registries {
+		if r.Name == "" {
+			return fmt.Errorf("a registry of application %s has no name", a.ID)
+		}
+
+		if names[r.Name] {
+			return fmt.Errorf("duplicated registry %s", r.Name)
+		}
+		names[r.Name] = true
+
+		if r.Existing != "" {
+			if r.Server != "" || r.Username != "" || r.Password != "" {
+				return fmt.Errorf("registry %s: cannot have both credentials and an existing secret", r.Name)
+			}
+
+			if errs := validation.IsDNS1123Subdomain(r.Existing); len(errs) > 0 {
+				return fmt.Errorf("registry %s: invalid existing secret %s: %s", r.Name, r.Existing, strings.Join(errs, ", "))
+			}
+
+			continue
+		}
+
+		if r.Server == "" {
+			return fmt.Errorf("registry %s has no server", r.Name)
+		}
+
+		if r.Username == "" {
+			return fmt.Errorf("registry %s has no username", r.Name)
+		}
+	}
+
+	if !isValidPullPolicy(a.PullPolicy) {
+		return fmt.Errorf("application %s: unknown pull policy %s", a.ID, a.PullPolicy)
+	}
+
+	if err := a.validatePullSecret(a.PullSecret); err != nil {
+		return fmt.Errorf("application %s: %s", a.ID, err)
+	}
+
+	for i := range a.Services {
+		s := &a.Services[i]
+
+		for _, image := range s.Images {
+			if !isValidPullPolicy(image.PullPolicy) {
+				return fmt.Errorf("service %s, image %s: unknown pull policy %s", s.Id, image.Name, image.PullPolicy)
+			}
+
+			if image.Local && image.PullPolicy != "" && image.PullPolicy != PullNever {
+				return fmt.Errorf("service %s, image %s: local images cannot be pulled", s.Id, image.Name)
+			}
+
+			if err := a.validatePullSecret(image.PullSecret); err != nil {
+				return fmt.Errorf("service %s, image %s: %s", s.Id, image.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package model
+
+import "fmt"
+
+const (
+	// Replicas can share a node
+	SpreadNone = "none"
+
+	// Replicas are placed on distinct nodes
+	SpreadNodes = "nodes"
+
+	// Replicas are placed on nodes of distinct zones
+	SpreadZones = "zones"
+)
+
+// Returns the number of instances of the service
+func (s *Service) GetReplicas() int {
+	if s.Replicas < 1 {
+		return 1
+	}
+
+	return s.Replicas
+}
+
+// Returns how the replicas of the service are spread over the nodes.
+// Replicas are placed on distinct nodes by default.
+func (s *Service) GetSpread() string {
+	if s.Spread == "" {
+		return SpreadNodes
+	}
+
+	return s.Spread
+}
+
+// Checks that the replicas of the service are well formed
+func (s *Service) validateReplicas() error {
+	if s.Replicas < 0 {
+		return fmt.Errorf("service %s: negative number of replicas", s.Id)
+	}
+
+	switch s.Spread {
+	case "", SpreadNone, SpreadNodes, SpreadZones:
+	default:
+		return fmt.Errorf("service %s: unknown spread policy %s", s.Id, s.Spread)
+	}
+
+	if s.GetReplicas() > 1 && s.NodeName != "" && s.GetSpread() != SpreadNone {
+		return fmt.Errorf("service %s: replicas pinned to node %s cannot be spread", s.Id, s.NodeName)
+	}
+
+	// Replicas sharing a node would compete for the same host ports
+	if s.GetReplicas() > 1 && s.GetSpread() == SpreadNone {
+		for _, image := range s.Images {
+			for _, port := range image.Ports {
+				if port.HostPort > 0 {
+					return fmt.Errorf("service %s: replicas that can share a node cannot use host ports", s.Id)
+				}
+			}
+		}
+	}
+
+	return nil
+}
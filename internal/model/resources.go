@@ -0,0 +1,91 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package model
+
+import "fmt"
+
+// Resources describes an amount of hardware resources.
+type Resources struct {
+	// CPU in millicores
+	CPU int64 `json:"cpu"`
+
+	// Memory in bytes
+	Memory int64 `json:"memory"`
+
+	// Ephemeral storage in bytes
+	EphemeralStorage int64 `json:"ephemeral_storage"`
+
+	// Kubernetes extended resources (e.g. example.com/gpu)
+	Extended map[string]int64 `json:"extended"`
+}
+
+// Returns true if no resources are specified
+func (r *Resources) IsEmpty() bool {
+	return r.CPU == 0 && r.Memory == 0 && r.EphemeralStorage == 0 && len(r.Extended) == 0
+}
+
+// ResourceRequirements describe the resources requested by a container and the maximum it can use.
+type ResourceRequirements struct {
+	Requests *Resources `json:"requests"`
+	Limits   *Resources `json:"limits"`
+}
+
+// Returns the sum of two sets of resources
+func (r Resources) Add(other Resources) Resources {
+	sum := Resources{
+		CPU:              r.CPU + other.CPU,
+		Memory:           r.Memory + other.Memory,
+		EphemeralStorage: r.EphemeralStorage + other.EphemeralStorage,
+		Extended:         make(map[string]int64),
+	}
+
+	for name, amount := range r.Extended {
+		sum.Extended[name] += amount
+	}
+
+	for name, amount := range other.Extended {
+		sum.Extended[name] += amount
+	}
+
+	return sum
+}
+
+// Checks that the requirements of a container are well formed
+func (r ResourceRequirements) validate(service *Service, image *Image) error {
+	for _, res := range []*Resources{r.Requests, r.Limits} {
+		if res == nil {
+			continue
+		}
+
+		if res.CPU < 0 || res.Memory < 0 || res.EphemeralStorage < 0 {
+			return fmt.Errorf("service %s, image %s: negative resources", service.Id, image.Name)
+		}
+
+		for name, amount := range res.Extended {
+			if amount < 0 {
+				return fmt.Errorf("service %s, image %s: negative amount of %s", service.Id, image.Name, name)
+			}
+		}
+	}
+
+	if r.Requests == nil || r.Limits == nil {
+		return nil
+	}
+
+	exceeds := func(request, limit int64) bool {
+		return limit > 0 && request > limit
+	}
+
+	if exceeds(r.Requests.CPU, r.Limits.CPU) || exceeds(r.Requests.Memory, r.Limits.Memory) ||
+		exceeds(r.Requests.EphemeralStorage, r.Limits.EphemeralStorage) {
+		return fmt.Errorf("service %s, image %s: requests exceed limits", service.Id, image.Name)
+	}
+
+	return nil
+}
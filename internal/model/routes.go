@@ -0,0 +1,117 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package model
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"strings"
+)
+
+// A Route exposes a port of a service to HTTP clients outside the cluster through an Ingress.
+type Route struct {
+	// Host of the requests. Empty matches every host
+	Host string `json:"host"`
+
+	// Path prefix of the requests. It defaults to /
+	Path string `json:"path"`
+
+	// Id of the target service and name of its exposed port
+	Service string `json:"service"`
+	Port    string `json:"port"`
+
+	// Secret with the TLS certificate of the host: a secret of the application or an existing Kubernetes Secret
+	TLSSecret string `json:"tls_secret"`
+}
+
+// Returns the path of the route
+func (r Route) GetPath() string {
+	if r.Path == "" {
+		return "/"
+	}
+
+	return r.Path
+}
+
+// Returns the port of a service with the given name
+func (s *Service) GetPort(name string) (Port, bool) {
+	for _, image := range s.Images {
+		for _, p := range image.Ports {
+			if p.Name == name {
+				return p, true
+			}
+		}
+	}
+
+	return Port{}, false
+}
+
+// Checks that the routes are well formed and target exposed ports of known services
+func (a *Application) validateRoutes() error {
+	paths := make(map[string]bool)
+	tlsSecrets := make(map[string]string)
+
+	for _, r := range a.Routes {
+		if r.Host != "" {
+			host := strings.TrimPrefix(r.Host, "*.")
+			if errs := validation.IsDNS1123Subdomain(host); len(errs) > 0 {
+				return fmt.Errorf("route: invalid host %s: %s", r.Host, strings.Join(errs, ", "))
+			}
+		}
+
+		if !strings.HasPrefix(r.GetPath(), "/") {
+			return fmt.Errorf("route %s%s: path must start with /", r.Host, r.Path)
+		}
+
+		key := r.Host + r.GetPath()
+		if paths[key] {
+			return fmt.Errorf("duplicated route %s", key)
+		}
+		paths[key] = true
+
+		s, exists := a.GetService(r.Service)
+		if !exists {
+			return fmt.Errorf("route %s: unknown service %s", key, r.Service)
+		}
+
+		p, exists := s.GetPort(r.Port)
+		if !exists {
+			return fmt.Errorf("route %s: service %s has no port %s", key, r.Service, r.Port)
+		}
+
+		if !p.IsExposed() || p.GetServiceType() == HeadlessService {
+			return fmt.Errorf("route %s: port %s of service %s is not exposed by a service with a virtual IP", key, r.Port, r.Service)
+		}
+
+		if p.GetProtocol() != "TCP" {
+			return fmt.Errorf("route %s: port %s of service %s is not a TCP port", key, r.Port, r.Service)
+		}
+
+		if r.TLSSecret == "" {
+			continue
+		}
+
+		if r.Host == "" {
+			return fmt.Errorf("route %s: a TLS secret requires a host", key)
+		}
+
+		if _, exists := a.GetSecret(r.TLSSecret); !exists {
+			if errs := validation.IsDNS1123Subdomain(r.TLSSecret); len(errs) > 0 {
+				return fmt.Errorf("route %s: invalid TLS secret %s: %s", key, r.TLSSecret, strings.Join(errs, ", "))
+			}
+		}
+
+		if secret, exists := tlsSecrets[r.Host]; exists && secret != r.TLSSecret {
+			return fmt.Errorf("host %s has different TLS secrets: %s and %s", r.Host, secret, r.TLSSecret)
+		}
+		tlsSecrets[r.Host] = r.TLSSecret
+	}
+
+	return nil
+}
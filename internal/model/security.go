@@ -0,0 +1,148 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package model
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Seccomp profiles of containers
+const (
+	// The default profile of the container runtime
+	SeccompRuntimeDefault = "runtime_default"
+
+	// No system call is filtered
+	SeccompUnconfined = "unconfined"
+
+	// Prefix of the profiles stored on the nodes, followed by the path of the profile
+	SeccompLocalhostPrefix = "localhost/"
+)
+
+// Valid names of Linux capabilities, without the CAP_ prefix
+var capabilityPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// A SecurityContext restricts the privileges of a container.
+type SecurityContext struct {
+	// User and group that run the processes of the container
+	RunAsUser  *int64 `json:"run_as_user"`
+	RunAsGroup *int64 `json:"run_as_group"`
+
+	// The container must not run as root
+	RunAsNonRoot *bool `json:"run_as_non_root"`
+
+	ReadOnlyRootFilesystem   bool  `json:"read_only_root_filesystem"`
+	AllowPrivilegeEscalation *bool `json:"allow_privilege_escalation"`
+
+	// Linux capabilities added to and dropped from the default ones, like NET_ADMIN or ALL
+	AddCapabilities  []string `json:"add_capabilities"`
+	DropCapabilities []string `json:"drop_capabilities"`
+
+	// runtime_default, unconfined or localhost/<profile>
+	SeccompProfile string `json:"seccomp_profile"`
+}
+
+// A Device is a device of the node made available to a container.
+type Device struct {
+	HostPath string `json:"host_path"`
+
+	// Path of the device in the container. It defaults to the host path
+	Path string `json:"path"`
+
+	ReadOnly bool `json:"read_only"`
+
+	// The device is a block device rather than a character device
+	Block bool `json:"block"`
+}
+
+// Returns the path of the device in the container
+func (d Device) GetPath() string {
+	if d.Path == "" {
+		return d.HostPath
+	}
+
+	return d.Path
+}
+
+// Returns the name of a capability without the CAP_ prefix, in upper case
+func NormalizeCapability(capability string) string {
+	return strings.TrimPrefix(strings.ToUpper(capability), "CAP_")
+}
+
+// Returns true if the container asks to run as root
+func (image *Image) RequestsRoot() bool {
+	if image.Security == nil {
+		return false
+	}
+
+	s := image.Security
+	return (s.RunAsUser != nil && *s.RunAsUser == 0) || (s.RunAsNonRoot != nil && !*s.RunAsNonRoot)
+}
+
+// Checks that the security settings and the devices of the container are well formed
+func (image *Image) validateSecurity(service *Service) error {
+	// The container runtime denies access to the devices of the node to containers that are not privileged
+	if len(image.Devices) > 0 && !image.Privileged {
+		return fmt.Errorf("service %s, image %s: devices can only be accessed by privileged containers", service.Id, image.Name)
+	}
+
+	paths := make(map[string]bool)
+	for _, d := range image.Devices {
+		if !strings.HasPrefix(path.Clean(d.HostPath), "/dev/") {
+			return fmt.Errorf("service %s, image %s: device %s is not in /dev", service.Id, image.Name, d.HostPath)
+		}
+
+		if !path.IsAbs(d.GetPath()) {
+			return fmt.Errorf("service %s, image %s: device path %s must be absolute", service.Id, image.Name, d.GetPath())
+		}
+
+		if paths[d.GetPath()] {
+			return fmt.Errorf("service %s, image %s: duplicated device path %s", service.Id, image.Name, d.GetPath())
+		}
+		paths[d.GetPath()] = true
+	}
+
+	s := image.Security
+	if s == nil {
+		return nil
+	}
+
+	if s.RunAsUser != nil && *s.RunAsUser < 0 {
+		return fmt.Errorf("service %s, image %s: invalid user %d", service.Id, image.Name, *s.RunAsUser)
+	}
+
+	if s.RunAsGroup != nil && *s.RunAsGroup < 0 {
+		return fmt.Errorf("service %s, image %s: invalid group %d", service.Id, image.Name, *s.RunAsGroup)
+	}
+
+	if s.RunAsNonRoot != nil && *s.RunAsNonRoot && s.RunAsUser != nil && *s.RunAsUser == 0 {
+		return fmt.Errorf("service %s, image %s: cannot run as user 0 and as non-root", service.Id, image.Name)
+	}
+
+	if image.Privileged && s.AllowPrivilegeEscalation != nil && !*s.AllowPrivilegeEscalation {
+		return fmt.Errorf("service %s, image %s: privileged containers always allow privilege escalation", service.Id, image.Name)
+	}
+
+	for _, c := range append(s.AddCapabilities, s.DropCapabilities...) {
+		if !capabilityPattern.MatchString(NormalizeCapability(c)) {
+			return fmt.Errorf("service %s, image %s: invalid capability %s", service.Id, image.Name, c)
+		}
+	}
+
+	switch {
+	case s.SeccompProfile == "", s.SeccompProfile == SeccompRuntimeDefault, s.SeccompProfile == SeccompUnconfined:
+	case strings.HasPrefix(s.SeccompProfile, SeccompLocalhostPrefix) && len(s.SeccompProfile) > len(SeccompLocalhostPrefix):
+	default:
+		return fmt.Errorf("service %s, image %s: unknown seccomp profile %s", service.Id, image.Name, s.SeccompProfile)
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package model
+
+import (
+	"testing"
+)
+
+func TestValidateSecurityDevices(t *testing.T) {
+	tests := []struct {
+		name  string
+		image Image
+		fails bool
+	}{
+		{"no devices", Image{Name: "app"}, false},
+		{"privileged", Image{Name: "app", Privileged: true, Devices: []Device{{HostPath: "/dev/ttyUSB0"}}}, false},
+		{"not privileged", Image{Name: "app", Devices: []Device{{HostPath: "/dev/ttyUSB0"}}}, true},
+		{"outside /dev", Image{Name: "app", Privileged: true, Devices: []Device{{HostPath: "/etc/passwd"}}}, true},
+		{"relative path", Image{Name: "app", Privileged: true, Devices: []Device{{HostPath: "/dev/ttyUSB0", Path: "tty"}}}, true},
+		{"duplicated path", Image{Name: "app", Privileged: true, Devices: []Device{{HostPath: "/dev/ttyUSB0"}, {HostPath: "/dev/ttyUSB1", Path: "/dev/ttyUSB0"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.image.validateSecurity(&Service{Id: "s"})
+			if (err != nil) != tt.fails {
+				t.Errorf("error = %v, want error %v", err, tt.fails)
+			}
+		})
+	}
+}
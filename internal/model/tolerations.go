@@ -0,0 +1,86 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package model
+
+import (
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+)
+
+// A Toleration allows a Service to run on nodes with a matching taint
+type Toleration struct {
+	Key      string `json:"key"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+	Effect   string `json:"effect"`
+}
+
+// Returns the Kubernetes toleration
+func (t Toleration) ToKubernetes() v1.Toleration {
+	op := v1.TolerationOperator(t.Operator)
+	if op == "" {
+		op = v1.TolerationOpEqual
+	}
+
+	return v1.Toleration{
+		Key:      t.Key,
+		Operator: op,
+		Value:    t.Value,
+		Effect:   v1.TaintEffect(t.Effect),
+	}
+}
+
+// Returns true if the toleration matches the taint
+func (t Toleration) Tolerates(taint *v1.Taint) bool {
+	k := t.ToKubernetes()
+	return k.ToleratesTaint(taint)
+}
+
+// Checks that the toleration is well formed
+func (t Toleration) validate(service *Service) error {
+	switch v1.TolerationOperator(t.Operator) {
+	case "", v1.TolerationOpEqual:
+		if t.Key == "" {
+			return fmt.Errorf("service %s: toleration with Equal operator requires a key", service.Id)
+		}
+	case v1.TolerationOpExists:
+		if t.Value != "" {
+			return fmt.Errorf("service %s: toleration with Exists operator cannot have a value", service.Id)
+		}
+	default:
+		return fmt.Errorf("service %s: unknown toleration operator %s", service.Id, t.Operator)
+	}
+
+	switch v1.TaintEffect(t.Effect) {
+	case "", v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute:
+	default:
+		return fmt.Errorf("service %s: unknown taint effect %s", service.Id, t.Effect)
+	}
+
+	return nil
+}
+
+// Returns true if the service tolerates all the taints of the node
+func (s *Service) ToleratesTaintsOf(node *Node) bool {
+	for i := range node.Taints {
+		tolerated := false
+		for _, t := range s.Tolerations {
+			if t.Tolerates(&node.Taints[i]) {
+				tolerated = true
+				break
+			}
+		}
+
+		if !tolerated {
+			return false
+		}
+	}
+
+	return true
+}
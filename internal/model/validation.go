@@ -0,0 +1,146 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package model
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"regexp"
+	"strings"
+)
+
+// Valid names of environment variables
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Returns the service with the given id
+func (a *Application) GetService(id string) (*Service, bool) {
+	for i := range a.Services {
+		if a.Services[i].Id == id {
+			return &a.Services[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// Checks that the application is well formed.
+// It returns the first error found.
+func (a *Application) Validate() error {
+	if a.ID == "" {
+		return fmt.Errorf("missing application id")
+	}
+
+	if a.Namespace != "" {
+		if errs := validation.IsDNS1123Label(a.Namespace); len(errs) > 0 {
+			return fmt.Errorf("invalid namespace %s: %s", a.Namespace, strings.Join(errs, ", "))
+		}
+	}
+
+	if len(a.Services) == 0 {
+		return fmt.Errorf("application %s has no services", a.ID)
+	}
+
+	ids := make(map[string]bool)
+	for i := range a.Services {
+		s := &a.Services[i]
+
+		if s.Id == "" {
+			return fmt.Errorf("a service of application %s has no id", a.ID)
+		}
+
+		if ids[s.Id] {
+			return fmt.Errorf("duplicated service id %s", s.Id)
+		}
+		ids[s.Id] = true
+	}
+
+	if err := a.validateConfigs(); err != nil {
+		return err
+	}
+
+	if err := a.validateAffinities(); err != nil {
+		return err
+	}
+
+	if err := a.validateRoutes(); err != nil {
+		return err
+	}
+
+	if err := a.validateRegistries(); err != nil {
+		return err
+	}
+
+	for _, f := range a.Flows {
+		if _, exists := a.GetService(f.Src); !exists {
+			return fmt.Errorf("flow from unknown service %s", f.Src)
+		}
+
+		if _, exists := a.GetService(f.Dst); !exists {
+			return fmt.Errorf("flow to unknown service %s", f.Dst)
+		}
+
+		if f.Env != "" && !envNamePattern.MatchString(f.Env) {
+			return fmt.Errorf("flow from %s to %s: invalid environment variable prefix %s", f.Src, f.Dst, f.Env)
+		}
+	}
+
+	for i := range a.Services {
+		s := &a.Services[i]
+
+		for _, c := range s.LocationConstraints {
+			if err := c.validate(a, s); err != nil {
+				return err
+			}
+		}
+
+		for _, t := range s.Tolerations {
+			if err := t.validate(s); err != nil {
+				return err
+			}
+		}
+
+		if err := s.validateVolumes(); err != nil {
+			return err
+		}
+
+		if err := s.validateReplicas(); err != nil {
+			return err
+		}
+
+		if err := s.validatePorts(); err != nil {
+			return err
+		}
+
+		for j := range s.Images {
+			image := &s.Images[j]
+
+			if err := image.validateContainer(s); err != nil {
+				return err
+			}
+
+			if err := image.validateSecurity(s); err != nil {
+				return err
+			}
+
+			if err := a.validateConfigUsage(s, image); err != nil {
+				return err
+			}
+
+			if image.Resources == nil {
+				continue
+			}
+
+			if err := image.Resources.validate(s, image); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package model
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"path"
+	"strings"
+)
+
+const (
+	// Scratch space that lives as long as the pod of the service
+	EmptyDirVolume = "empty_dir"
+
+	// A directory of the node hosting the service
+	HostPathVolume = "host_path"
+
+	// A PersistentVolumeClaim created for the service
+	PersistentVolume = "persistent"
+)
+
+// A Volume is storage attached to the containers of a Service
+type Volume struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	// Directory of the node (host_path only)
+	Path string `json:"path"`
+
+	// Size in bytes. It is the capacity requested by persistent volumes and the size limit of empty_dir volumes
+	Size int64 `json:"size"`
+
+	// Storage class of a persistent volume. Empty uses the default storage class of the cluster
+	StorageClass string `json:"storage_class"`
+}
+
+// A VolumeMount mounts a volume of the service in a directory of the container
+type VolumeMount struct {
+	Volume   string `json:"volume"`
+	Path     string `json:"path"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+// Returns the volume with the given name
+func (s *Service) GetVolume(name string) (*Volume, bool) {
+	for i := range s.Volumes {
+		if s.Volumes[i].Name == name {
+			return &s.Volumes[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// Returns true if the service stores data in persistent volumes
+func (s *Service) HasPersistentVolumes() bool {
+	for _, v := range s.Volumes {
+		if v.Type == PersistentVolume {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the storage classes that the node of the service must provide
+func (s *Service) GetStorageClasses() []string {
+	classes := make([]string, 0)
+	for _, v := range s.Volumes {
+		if v.Type == PersistentVolume && v.StorageClass != "" {
+			classes = append(classes, v.StorageClass)
+		}
+	}
+
+	return classes
+}
+
+// Returns true if the node provides all the storage classes required by the service
+func (s *Service) HasStorageOn(node *Node) bool {
+	for _, class := range s.GetStorageClasses() {
+		provided := false
+		for _, c := range node.StorageClasses {
+			if c == class {
+				provided = true
+				break
+			}
+		}
+
+		if !provided {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Checks that the volumes of the service and their mounts are well formed
+func (s *Service) validateVolumes() error {
+	names := make(map[string]bool)
+	for _, v := range s.Volumes {
+		if errs := validation.IsDNS1123Label(v.Name); len(errs) > 0 {
+			return fmt.Errorf("service %s: invalid volume name %s: %s", s.Id, v.Name, strings.Join(errs, ", "))
+		}
+
+		if names[v.Name] {
+			return fmt.Errorf("service %s: duplicated volume %s", s.Id, v.Name)
+		}
+		names[v.Name] = true
+
+		if v.Size < 0 {
+			return fmt.Errorf("service %s: volume %s has a negative size", s.Id, v.Name)
+		}
+
+		switch v.Type {
+		case EmptyDirVolume:
+		case HostPathVolume:
+			if !path.IsAbs(v.Path) {
+				return fmt.Errorf("service %s: volume %s requires an absolute host path", s.Id, v.Name)
+			}
+		case PersistentVolume:
+			if v.Size == 0 {
+				return fmt.Errorf("service %s: persistent volume %s requires a size", s.Id, v.Name)
+			}
+		default:
+			return fmt.Errorf("service %s: unknown type %s of volume %s", s.Id, v.Type, v.Name)
+		}
+	}
+
+	for _, image := range s.Images {
+		for _, m := range image.VolumeMounts {
+			if _, exists := s.GetVolume(m.Volume); !exists {
+				return fmt.Errorf("service %s, image %s: unknown volume %s", s.Id, image.Name, m.Volume)
+			}
+
+			if !path.IsAbs(m.Path) {
+				return fmt.Errorf("service %s, image %s: mount path %s is not absolute", s.Id, image.Name, m.Path)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,265 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package config
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CPUWeightName              = "cpu"
+	MemoryWeightName           = "memory"
+	EphemeralStorageWeightName = "ephemeral-storage"
+)
+
+// Failure policies of a deploy
+const (
+	// Remove every object created by the failed deploy
+	RollbackOnFailure = "rollback"
+
+	// Leave the objects created by the failed deploy on the cluster
+	KeepOnFailure = "keep"
+)
+
+// A Config stores the settings of FogLute.
+type Config struct {
+	// Weights used to reduce node capacities and service requirements to the HW term of the analyzer
+	ResourceWeights ResourceWeights
+
+	// Resources requested for each unit of the HW requirements of services without structured resources
+	HWUnit ResourceQuantities
+
+	// Label selector of the nodes that are part of the fog infrastructure. Empty selects all nodes
+	NodeSelector string
+
+	// Namespace of the applications that do not declare one
+	Namespace string
+
+	// What to do with the objects created by a failed deploy
+	FailurePolicy string
+
+	// Maximum time to wait for the services of an application to be ready. Zero disables waiting
+	RolloutTimeout time.Duration
+
+	// Maximum number of times services that fail on their node are placed again. Zero disables re-placement
+	MaxReplacements int
+
+	// Allow services with persistent volumes to be placed away from the node that holds their data
+	ForceRelocation bool
+
+	// Label selector of the nodes that run the ingress controller. Empty selects all nodes
+	IngressNodeSelector string
+
+	// Ingress class of the Ingresses of applications. Empty uses the default ingress controller
+	IngressClass string
+
+	// Privileges that applications can request
+	SecurityPolicy SecurityPolicy
+
+	// Settings that enforce the security requirements of services
+	SecurityMappings SecurityMappings
+
+	// Restrict the traffic of the services of every application to its declared flows
+	FlowPolicies bool
+
+	// Limit the traffic of pods to the bandwidth of the flows of their services
+	BandwidthShaping bool
+
+	// Kubeconfig contexts of the clusters managed by FogLute. Empty manages the cluster of the current context
+	Contexts StringList
+
+	// Latency, in ms, and bandwidth, in Mbps, of the links between nodes of different clusters
+	InterClusterLatency   int
+	InterClusterBandwidth int
+}
+
+// A SecurityPolicy lists the privileges that containers can request.
+// Applications requesting other privileges are rejected.
+type SecurityPolicy struct {
+	// Containers can run in privileged mode
+	AllowPrivileged bool
+
+	// Linux capabilities that containers can add, without the CAP_ prefix. ALL allows every capability
+	AllowedCapabilities StringList
+
+	// Devices of the nodes that containers can access, as path patterns like /dev/ttyUSB*
+	AllowedDevices StringList
+
+	// Directories of the nodes that host_path volumes can mount, as path prefixes like /var/log.
+	// Containers can only read them, unless the prefix is followed by :rw
+	AllowedHostPaths StringList
+
+	// Containers can disable seccomp filtering
+	AllowUnconfined bool
+
+	// Containers that are not privileged must run as non-root users
+	RequireNonRoot bool
+}
+
+// Returns a Config with default values
+func NewDefaultConfig() *Config {
+	return &Config{
+		ResourceWeights: ResourceWeights{
+			CPU:      1,
+			Memory:   1,
+			Extended: make(map[string]float64),
+		},
+		Namespace:      "default",
+		FailurePolicy:  RollbackOnFailure,
+		RolloutTimeout: 2 * time.Minute,
+		SecurityPolicy: SecurityPolicy{
+			AllowPrivileged: true,
+		},
+		InterClusterLatency:   50,
+		InterClusterBandwidth: 100,
+	}
+}
+
+// ResourceWeights define how many HW units a resource is worth.
+// It implements flag.Value, parsing lists like "cpu=1,memory=0.5,example.com/gpu=4".
+type ResourceWeights struct {
+	// HW units per CPU core
+	CPU float64
+
+	// HW units per GiB of memory
+	Memory float64
+
+	// HW units per GiB of ephemeral storage
+	EphemeralStorage float64
+
+	// HW units per unit of an extended resource
+	Extended map[string]float64
+}
+
+func (w *ResourceWeights) String() string {
+	if w == nil {
+		return ""
+	}
+
+	weights := []string{
+		fmt.Sprintf("%s=%g", CPUWeightName, w.CPU),
+		fmt.Sprintf("%s=%g", MemoryWeightName, w.Memory),
+		fmt.Sprintf("%s=%g", EphemeralStorageWeightName, w.EphemeralStorage),
+	}
+
+	extended := make([]string, 0, len(w.Extended))
+	for name, weight := range w.Extended {
+		extended = append(extended, fmt.Sprintf("%s=%g", name, weight))
+	}
+	sort.Strings(extended)
+
+	return strings.Join(append(weights, extended...), ",")
+}
+
+func (w *ResourceWeights) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid resource weight: %s", pair)
+		}
+
+		weight, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || weight < 0 {
+			return fmt.Errorf("invalid weight for %s: %s", parts[0], parts[1])
+		}
+
+		switch parts[0] {
+		case CPUWeightName:
+			w.CPU = weight
+		case MemoryWeightName:
+			w.Memory = weight
+		case EphemeralStorageWeightName:
+			w.EphemeralStorage = weight
+		default:
+			if w.Extended == nil {
+				w.Extended = make(map[string]float64)
+			}
+			w.Extended[parts[0]] = weight
+		}
+	}
+
+	return nil
+}
+
+// ResourceQuantities map resource names to Kubernetes quantities.
+// It implements flag.Value, parsing lists like "cpu=500m,memory=512Mi,example.com/gpu=1".
+type ResourceQuantities map[string]resource.Quantity
+
+func (q *ResourceQuantities) String() string {
+	if q == nil {
+		return ""
+	}
+
+	quantities := make([]string, 0, len(*q))
+	for name, quantity := range *q {
+		quantities = append(quantities, fmt.Sprintf("%s=%s", name, quantity.String()))
+	}
+	sort.Strings(quantities)
+
+	return strings.Join(quantities, ",")
+}
+
+func (q *ResourceQuantities) Set(value string) error {
+	quantities := make(ResourceQuantities)
+
+	for _, pair := range strings.Split(value, ",") {
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid resource quantity: %s", pair)
+		}
+
+		quantity, err := resource.ParseQuantity(parts[1])
+		if err != nil {
+			return fmt.Errorf("invalid quantity for %s: %s", parts[0], err)
+		}
+
+		quantities[parts[0]] = quantity
+	}
+
+	*q = quantities
+
+	return nil
+}
+
+// A StringList implements flag.Value, parsing comma separated lists like "NET_ADMIN,SYS_TIME".
+type StringList []string
+
+func (l *StringList) String() string {
+	if l == nil {
+		return ""
+	}
+
+	return strings.Join(*l, ",")
+}
+
+func (l *StringList) Set(value string) error {
+	list := make(StringList, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	*l = list
+
+	return nil
+}
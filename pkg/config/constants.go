@@ -19,6 +19,22 @@ const (
 	IotCapsLabelName   = "iot_caps"
 	SecCapsLabelName   = "sec_caps"
 	HwCapsLabelName    = "hw_caps"
+	ZoneLabelName      = "zone"
+
+	StorageClassesLabelName = "storage_classes"
+
+	AppLabelName     = "app"
+	ServiceLabelName = "service"
+	ReplicaLabelName = "replica"
+
+	// Well-known Kubernetes label identifying the tool that manages an object
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	ManagedByValue = "foglute"
+
+	AppIDAnnotationName        = "application-id"
+	AppNameAnnotationName      = "application-name"
+	ServiceIDAnnotationName    = "service-id"
+	OriginalNameAnnotationName = "original-name"
 )
 
 var LongitudeLabel string
@@ -26,6 +42,15 @@ var LatitudeLabel string
 var IotLabel string
 var SecLabel string
 var HwCapsLabel string
+var ZoneLabel string
+var StorageClassesLabel string
+var AppLabel string
+var ServiceLabel string
+var ReplicaLabel string
+var AppIDAnnotation string
+var AppNameAnnotation string
+var ServiceIDAnnotation string
+var OriginalNameAnnotation string
 
 func init() {
 	LongitudeLabel = fmt.Sprintf("%s/%s", FoglutePackageName, LongitudeLabelName)
@@ -33,4 +58,13 @@ func init() {
 	IotLabel = fmt.Sprintf("%s/%s", FoglutePackageName, IotCapsLabelName)
 	SecLabel = fmt.Sprintf("%s/%s", FoglutePackageName, SecCapsLabelName)
 	HwCapsLabel = fmt.Sprintf("%s/%s", FoglutePackageName, HwCapsLabelName)
+	ZoneLabel = fmt.Sprintf("%s/%s", FoglutePackageName, ZoneLabelName)
+	StorageClassesLabel = fmt.Sprintf("%s/%s", FoglutePackageName, StorageClassesLabelName)
+	AppLabel = fmt.Sprintf("%s/%s", FoglutePackageName, AppLabelName)
+	ServiceLabel = fmt.Sprintf("%s/%s", FoglutePackageName, ServiceLabelName)
+	ReplicaLabel = fmt.Sprintf("%s/%s", FoglutePackageName, ReplicaLabelName)
+	AppIDAnnotation = fmt.Sprintf("%s/%s", FoglutePackageName, AppIDAnnotationName)
+	AppNameAnnotation = fmt.Sprintf("%s/%s", FoglutePackageName, AppNameAnnotationName)
+	ServiceIDAnnotation = fmt.Sprintf("%s/%s", FoglutePackageName, ServiceIDAnnotationName)
+	OriginalNameAnnotation = fmt.Sprintf("%s/%s", FoglutePackageName, OriginalNameAnnotationName)
 }
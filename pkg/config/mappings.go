@@ -0,0 +1,75 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"foglute/internal/model"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"os"
+	"strings"
+)
+
+// Network isolations of the pods of a service
+const (
+	// Pods accept connections only from the pods of their application and on exposed ports
+	IsolateIngress = "ingress"
+
+	// Pods open connections only to the pods of their application and to DNS servers
+	IsolateEgress = "egress"
+)
+
+// A SecurityMapping lists the Kubernetes settings that enforce a security capability on the services requiring it.
+type SecurityMapping struct {
+	// Runtime class of the pods
+	RuntimeClass string `json:"runtime_class"`
+
+	// Security settings of the containers, combined with the ones of the images
+	Security *model.SecurityContext `json:"security"`
+
+	// Network isolation of the pods: ingress, egress or both
+	Isolation []string `json:"isolation"`
+
+	// Annotations of the pods
+	Annotations map[string]string `json:"annotations"`
+}
+
+// SecurityMappings map the names of security capabilities, as in sec_reqs and sec_caps, to their settings.
+type SecurityMappings map[string]SecurityMapping
+
+// Reads the security mappings from a JSON file
+func LoadSecurityMappings(path string) (SecurityMappings, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mappings SecurityMappings
+	if err := json.NewDecoder(f).Decode(&mappings); err != nil {
+		return nil, fmt.Errorf("cannot parse security mappings %s: %s", path, err)
+	}
+
+	for name, m := range mappings {
+		if m.RuntimeClass != "" {
+			if errs := validation.IsDNS1123Subdomain(m.RuntimeClass); len(errs) > 0 {
+				return nil, fmt.Errorf("security mapping %s: invalid runtime class %s: %s", name, m.RuntimeClass, strings.Join(errs, ", "))
+			}
+		}
+
+		for _, i := range m.Isolation {
+			if i != IsolateIngress && i != IsolateEgress {
+				return nil, fmt.Errorf("security mapping %s: unknown isolation %s", name, i)
+			}
+		}
+	}
+
+	return mappings, nil
+}
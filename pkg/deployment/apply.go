@@ -0,0 +1,293 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"foglute/internal/model"
+	"foglute/pkg/config"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"log"
+	"time"
+)
+
+const (
+	// Annotation that stores the hash of the desired state of an object
+	specHashAnnotationName = "spec-hash"
+
+	// Polling settings used while waiting for an object to be deleted before creating it again
+	recreatePollInterval = time.Second
+	recreateTimeout      = 2 * time.Minute
+)
+
+var specHashAnnotation = fmt.Sprintf("%s/%s", config.FoglutePackageName, specHashAnnotationName)
+
+// The outcome of an operation on a Kubernetes object
+type ApplyAction string
+
+const (
+	ActionCreated   ApplyAction = "created"
+	ActionUpdated   ApplyAction = "updated"
+	ActionRecreated ApplyAction = "recreated"
+	ActionUnchanged ApplyAction = "unchanged"
+	ActionDeleted   ApplyAction = "deleted"
+
+	// The object to delete does not exist
+	ActionAbsent ApplyAction = "absent"
+
+	ActionFailed ApplyAction = "failed"
+)
+
+// An ObjectResult reports the outcome of an operation on a Kubernetes object.
+type ObjectResult struct {
+	Kind   string      `json:"kind"`
+	Name   string      `json:"name"`
+	Action ApplyAction `json:"action"`
+	Error  string      `json:"error,omitempty"`
+
+	// Kubernetes cluster of the object, if FogLute manages many clusters
+	Cluster string `json:"cluster,omitempty"`
+}
+
+func newObjectResult(kind string, name string, action ApplyAction, err error) ObjectResult {
+	r := ObjectResult{
+		Kind:   kind,
+		Name:   name,
+		Action: action,
+	}
+
+	if err != nil {
+		r.Action = ActionFailed
+		r.Error = err.Error()
+	}
+
+	return r
+}
+
+// Stores in the object annotations a hash of its desired state.
+// The hash lets apply skip objects that did not change since the last update.
+func setSpecHash(object metav1.Object, spec interface{}) {
+	annotations := object.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	delete(annotations, specHashAnnotation)
+
+	b, _ := json.Marshal(struct {
+		Labels          map[string]string
+		Annotations     map[string]string
+		OwnerReferences []metav1.OwnerReference
+		Spec            interface{}
+	}{object.GetLabels(), annotations, object.GetOwnerReferences(), spec})
+
+	annotations[specHashAnnotation] = fmt.Sprintf("%x", sha256.Sum256(b))[:16]
+	object.SetAnnotations(annotations)
+}
+
+// Returns the state of an object compared by apply
+func getAppliedState(client *objectClient, object metav1.Object) interface{} {
+	b, _ := json.Marshal(struct {
+		Labels          map[string]string
+		Annotations     map[string]string
+		OwnerReferences []metav1.OwnerReference
+		Spec            interface{}
+	}{object.GetLabels(), object.GetAnnotations(), object.GetOwnerReferences(), client.spec(object)})
+
+	var state interface{}
+	_ = json.Unmarshal(b, &state)
+
+	return state
+}
+
+// Returns true if the existing object was applied with the same desired state and was not changed since then.
+// The spec hash detects changes of the desired state, while the comparison of the states detects changes made to
+// the existing object without FogLute.
+func isApplied(client *objectClient, desired metav1.Object, existing metav1.Object) bool {
+	if existing.GetAnnotations()[specHashAnnotation] != desired.GetAnnotations()[specHashAnnotation] {
+		return false
+	}
+
+	return containsState(getAppliedState(client, desired), getAppliedState(client, existing))
+}
+
+// Returns true if every field set in the desired state has the same value in the existing state.
+// Fields that are not set in the desired state, such as those defaulted by Kubernetes, are ignored,
+// while lists must have the same length.
+func containsState(desired interface{}, existing interface{}) bool {
+	switch d := desired.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		e, ok := existing.(map[string]interface{})
+		if !ok {
+			return len(d) == 0 && existing == nil
+		}
+
+		for key, value := range d {
+			if !containsState(value, e[key]) {
+				return false
+			}
+		}
+
+		return true
+	case []interface{}:
+		e, ok := existing.([]interface{})
+		if !ok {
+			return len(d) == 0 && existing == nil
+		}
+
+		if len(e) != len(d) {
+			return false
+		}
+
+		for i := range d {
+			if !containsState(d[i], e[i]) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		return desired == existing
+	}
+}
+
+// Creates or updates an object.
+// Existing objects that are not managed by FogLute for the application are never changed.
+func (manager *Manager) applyObject(application *model.Application, client *objectClient, desired metav1.Object) ObjectResult {
+	name := desired.GetName()
+
+	setSpecHash(desired, client.spec(desired))
+
+	existing, err := client.get(name)
+	if errors.IsNotFound(err) {
+		return newObjectResult(client.kind, name, ActionCreated, client.create(desired))
+	}
+
+	if err != nil {
+		return newObjectResult(client.kind, name, ActionFailed, err)
+	}
+
+	if !isOwnedBy(existing, application) {
+		return newObjectResult(client.kind, name, ActionFailed, fmt.Errorf("not managed by FogLute for application %s", application.ID))
+	}
+
+	if isApplied(client, desired, existing) {
+		return newObjectResult(client.kind, name, ActionUnchanged, nil)
+	}
+
+	if client.recreate != nil && client.recreate(desired, existing) {
+		return newObjectResult(client.kind, name, ActionRecreated, manager.recreateObject(client, desired))
+	}
+
+	desired.SetResourceVersion(existing.GetResourceVersion())
+	if client.preserve != nil {
+		client.preserve(desired, existing)
+	}
+
+	return newObjectResult(client.kind, name, ActionUpdated, client.update(desired))
+}
+
+// Deletes an object and creates it again once it is gone
+func (manager *Manager) recreateObject(client *objectClient, desired metav1.Object) error {
+	name := desired.GetName()
+
+	log.Printf("Recreating %s %s\n", client.kind, name)
+
+	if r := manager.deleteObject(client, name); r.Action == ActionFailed {
+		return fmt.Errorf("cannot delete %s: %s", name, r.Error)
+	}
+
+	err := wait.PollImmediate(recreatePollInterval, recreateTimeout, func() (bool, error) {
+		_, err := client.get(name)
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+
+		return false, err
+	})
+	if err != nil {
+		return fmt.Errorf("cannot wait for %s to be deleted: %s", name, err)
+	}
+
+	return client.create(desired)
+}
+
+// Deletes an object. A missing object is not an error.
+func (manager *Manager) deleteObject(client *objectClient, name string) ObjectResult {
+	deletePolicy := metav1.DeletePropagationForeground
+
+	err := client.delete(name, &metav1.DeleteOptions{
+		PropagationPolicy: &deletePolicy,
+	})
+	if errors.IsNotFound(err) {
+		return newObjectResult(client.kind, name, ActionAbsent, nil)
+	}
+
+	return newObjectResult(client.kind, name, ActionDeleted, err)
+}
+
+// Deletes the objects of an application that are not part of its desired state anymore.
+// The owner of the application is never pruned.
+func (manager *Manager) pruneObjects(application *model.Application, namespace string, objects *applicationObjects) []ObjectResult {
+	results := make([]ObjectResult, 0)
+
+	selector := labels.SelectorFromSet(map[string]string{
+		config.AppLabel:       labelValue(application.ID),
+		config.ManagedByLabel: config.ManagedByValue,
+	}).String()
+
+	groups := manager.getObjectGroups(namespace, objects)
+
+	// Dependent objects are deleted before the objects they reference
+	for i := len(groups) - 1; i >= 0; i-- {
+		group := groups[i]
+
+		desired := make(map[string]bool)
+		for _, o := range group.objects {
+			desired[o.GetName()] = true
+		}
+
+		if group.client.kind == "ConfigMap" {
+			desired[getOwnerName(application)] = true
+		}
+
+		existing, err := group.client.list(metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			results = append(results, newObjectResult(group.client.kind, "", ActionFailed, err))
+			continue
+		}
+
+		for _, o := range existing {
+			if !desired[o.GetName()] && isOwnedBy(o, application) {
+				log.Printf("Pruning %s %s\n", group.client.kind, o.GetName())
+				results = append(results, manager.deleteObject(group.client, o.GetName()))
+			}
+		}
+	}
+
+	return results
+}
+
+// Returns the errors reported by a list of results
+func getResultErrors(results []ObjectResult) []error {
+	errs := make([]error, 0)
+	for _, r := range results {
+		if r.Action == ActionFailed {
+			errs = append(errs, fmt.Errorf("%s %s: %s", r.Kind, r.Name, r.Error))
+		}
+	}
+
+	return errs
+}
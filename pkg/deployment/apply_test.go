@@ -0,0 +1,392 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"encoding/json"
+	"foglute/internal/model"
+	"foglute/pkg/config"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"reflect"
+	"testing"
+)
+
+// An in-memory objectClient of ConfigMaps that records the operations performed on it
+type fakeClient struct {
+	objects    map[string]*apiv1.ConfigMap
+	operations []string
+}
+
+func newFakeClient(existing ...*apiv1.ConfigMap) *fakeClient {
+	f := &fakeClient{objects: make(map[string]*apiv1.ConfigMap)}
+	for _, o := range existing {
+		f.objects[o.Name] = o.DeepCopy()
+	}
+
+	return f
+}
+
+func (f *fakeClient) client(recreate func(desired metav1.Object, existing metav1.Object) bool) *objectClient {
+	notFound := func(name string) error {
+		return errors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, name)
+	}
+
+	return &objectClient{
+		kind: "ConfigMap",
+		get: func(name string) (metav1.Object, error) {
+			o, exists := f.objects[name]
+			if !exists {
+				return nil, notFound(name)
+			}
+			return o.DeepCopy(), nil
+		},
+		create: func(object metav1.Object) error {
+			f.operations = append(f.operations, "create "+object.GetName())
+			f.objects[object.GetName()] = object.(*apiv1.ConfigMap).DeepCopy()
+			return nil
+		},
+		update: func(object metav1.Object) error {
+			f.operations = append(f.operations, "update "+object.GetName())
+			f.objects[object.GetName()] = object.(*apiv1.ConfigMap).DeepCopy()
+			return nil
+		},
+		delete: func(name string, options *metav1.DeleteOptions) error {
+			if _, exists := f.objects[name]; !exists {
+				return notFound(name)
+			}
+			f.operations = append(f.operations, "delete "+name)
+			delete(f.objects, name)
+			return nil
+		},
+		spec: func(object metav1.Object) interface{} {
+			return object.(*apiv1.ConfigMap).Data
+		},
+		preserve: func(desired metav1.Object, existing metav1.Object) {
+			desired.(*apiv1.ConfigMap).BinaryData = existing.(*apiv1.ConfigMap).BinaryData
+		},
+		recreate: recreate,
+	}
+}
+
+func newConfigMap(application *model.Application, name string, value string) *apiv1.ConfigMap {
+	return &apiv1.ConfigMap{
+		ObjectMeta: getApplicationObjectMeta(application, name, ""),
+		Data:       map[string]string{"key": value},
+	}
+}
+
+// Returns a copy of an object as stored after a previous apply
+func applied(o *apiv1.ConfigMap) *apiv1.ConfigMap {
+	c := o.DeepCopy()
+	setSpecHash(c, c.Data)
+	c.ResourceVersion = "1"
+	c.BinaryData = map[string][]byte{"allocated": []byte("by kubernetes")}
+
+	return c
+}
+
+func TestSetSpecHash(t *testing.T) {
+	application := &model.Application{ID: "app"}
+	hash := func(o *apiv1.ConfigMap) string {
+		setSpecHash(o, o.Data)
+		return o.Annotations[specHashAnnotation]
+	}
+
+	base := newConfigMap(application, "config", "value")
+	baseHash := hash(base.DeepCopy())
+
+	tests := []struct {
+		name    string
+		modify  func(o *apiv1.ConfigMap)
+		changed bool
+	}{
+		{"same object", func(o *apiv1.ConfigMap) {}, false},
+		{"previous hash", func(o *apiv1.ConfigMap) { o.Annotations[specHashAnnotation] = "0123456789abcdef" }, false},
+		{"status fields", func(o *apiv1.ConfigMap) { o.ResourceVersion = "42"; o.UID = "uid" }, false},
+		{"spec", func(o *apiv1.ConfigMap) { o.Data["key"] = "other" }, true},
+		{"label", func(o *apiv1.ConfigMap) { o.Labels["extra"] = "label" }, true},
+		{"annotation", func(o *apiv1.ConfigMap) { o.Annotations["extra"] = "annotation" }, true},
+		{"owner", func(o *apiv1.ConfigMap) {
+			o.OwnerReferences = []metav1.OwnerReference{{Kind: "ConfigMap", Name: "owner", UID: "uid"}}
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := base.DeepCopy()
+			tt.modify(o)
+
+			got := hash(o)
+			if len(got) != 16 {
+				t.Errorf("hash %q has length %d, want 16", got, len(got))
+			}
+
+			if (got != baseHash) != tt.changed {
+				t.Errorf("hash changed = %v, want %v", got != baseHash, tt.changed)
+			}
+		})
+	}
+}
+
+func TestContainsState(t *testing.T) {
+	state := func(s string) interface{} {
+		var v interface{}
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			t.Fatalf("invalid state %s: %s", s, err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		name     string
+		desired  string
+		existing string
+		want     bool
+	}{
+		{"same state", `{"a":1,"b":["x","y"]}`, `{"a":1,"b":["x","y"]}`, true},
+		{"defaulted field", `{"a":1}`, `{"a":1,"b":"default"}`, true},
+		{"defaulted nested field", `{"a":[{"b":1}]}`, `{"a":[{"b":1,"c":2}]}`, true},
+		{"unset field", `{"a":null}`, `{"a":"default"}`, true},
+		{"empty and missing map", `{"a":{}}`, `{}`, true},
+		{"changed value", `{"a":1}`, `{"a":2}`, false},
+		{"changed type", `{"a":"1"}`, `{"a":1}`, false},
+		{"missing field", `{"a":1}`, `{}`, false},
+		{"added list item", `{"a":["x"]}`, `{"a":["x","y"]}`, false},
+		{"removed list item", `{"a":["x","y"]}`, `{"a":["x"]}`, false},
+		{"reordered list", `{"a":["x","y"]}`, `{"a":["y","x"]}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := containsState(state(tt.desired), state(tt.existing)); got != tt.want {
+				t.Errorf("containsState(%s, %s) = %v, want %v", tt.desired, tt.existing, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyObject(t *testing.T) {
+	application := &model.Application{ID: "app"}
+	desired := newConfigMap(application, "config", "value")
+
+	foreign := newConfigMap(&model.Application{ID: "other"}, "config", "value")
+
+	unmanaged := desired.DeepCopy()
+	unmanaged.Labels = nil
+	unmanaged.Annotations = nil
+
+	outdated := applied(newConfigMap(application, "config", "old"))
+
+	// Changed on the cluster without changing its spec hash
+	edited := applied(desired)
+	edited.Data["key"] = "edited"
+
+	tests := []struct {
+		name       string
+		existing   []*apiv1.ConfigMap
+		recreate   bool
+		action     ApplyAction
+		operations []string
+		preserved  bool
+	}{
+		{"missing", nil, false, ActionCreated, []string{"create config"}, false},
+		{"unchanged", []*apiv1.ConfigMap{applied(desired)}, false, ActionUnchanged, nil, true},
+		{"changed", []*apiv1.ConfigMap{outdated}, false, ActionUpdated, []string{"update config"}, true},
+		{"edited", []*apiv1.ConfigMap{edited}, false, ActionUpdated, []string{"update config"}, true},
+		{"changed immutable fields", []*apiv1.ConfigMap{outdated}, true, ActionRecreated, []string{"delete config", "create config"}, false},
+		{"unchanged immutable fields", []*apiv1.ConfigMap{applied(desired)}, true, ActionUnchanged, nil, true},
+		{"other application", []*apiv1.ConfigMap{foreign}, false, ActionFailed, nil, false},
+		{"not managed", []*apiv1.ConfigMap{unmanaged}, false, ActionFailed, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeClient(tt.existing...)
+			recreate := func(desired metav1.Object, existing metav1.Object) bool { return tt.recreate }
+
+			manager := &Manager{}
+			r := manager.applyObject(application, f.client(recreate), desired.DeepCopy())
+
+			if r.Action != tt.action {
+				t.Fatalf("action = %s (%s), want %s", r.Action, r.Error, tt.action)
+			}
+
+			if !reflect.DeepEqual(f.operations, tt.operations) {
+				t.Errorf("operations = %v, want %v", f.operations, tt.operations)
+			}
+
+			if tt.action == ActionFailed {
+				if r.Error == "" {
+					t.Errorf("failed result without error")
+				}
+				return
+			}
+
+			stored := f.objects["config"]
+			if stored.Data["key"] != "value" {
+				t.Errorf("stored data = %v, want value", stored.Data)
+			}
+
+			if stored.Annotations[specHashAnnotation] == "" {
+				t.Errorf("stored object has no spec hash")
+			}
+
+			if preserved := stored.BinaryData != nil; preserved != tt.preserved {
+				t.Errorf("fields set by Kubernetes preserved = %v, want %v", preserved, tt.preserved)
+			}
+
+			if tt.action == ActionUpdated && stored.ResourceVersion != "1" {
+				t.Errorf("update without the resource version of the existing object")
+			}
+		})
+	}
+}
+
+func TestRollback(t *testing.T) {
+	application := &model.Application{ID: "app"}
+
+	tests := []struct {
+		name       string
+		existing   []string
+		created    []string
+		operations []string
+		remaining  []string
+		errors     int
+	}{
+		{"nothing created", []string{"kept"}, nil, nil, []string{"kept"}, 0},
+		{"created objects", []string{"kept", "a", "b", "c"}, []string{"a", "b", "c"}, []string{"delete c", "delete b", "delete a"}, []string{"kept"}, 0},
+		{"already deleted", []string{"a"}, []string{"a", "b"}, []string{"delete a"}, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := make([]*apiv1.ConfigMap, len(tt.existing))
+			for i, name := range tt.existing {
+				existing[i] = newConfigMap(application, name, "value")
+			}
+
+			f := newFakeClient(existing...)
+			client := f.client(nil)
+
+			created := &createdObjects{}
+			for _, name := range tt.created {
+				created.dependents = append(created.dependents, createdObject{client, name})
+			}
+
+			manager := &Manager{}
+			errs := manager.rollback(application, "default", created)
+
+			if len(errs) != tt.errors {
+				t.Errorf("errors = %v, want %d", errs, tt.errors)
+			}
+
+			if !reflect.DeepEqual(f.operations, tt.operations) {
+				t.Errorf("operations = %v, want %v", f.operations, tt.operations)
+			}
+
+			if len(f.objects) != len(tt.remaining) {
+				t.Errorf("%d objects left, want %v", len(f.objects), tt.remaining)
+			}
+			for _, name := range tt.remaining {
+				if _, exists := f.objects[name]; !exists {
+					t.Errorf("object %s deleted", name)
+				}
+			}
+		})
+	}
+}
+
+func TestPreserveServiceAllocations(t *testing.T) {
+	service := func(t apiv1.ServiceType, policy apiv1.ServiceExternalTrafficPolicyType, ports ...apiv1.ServicePort) *apiv1.Service {
+		return &apiv1.Service{Spec: apiv1.ServiceSpec{Type: t, ExternalTrafficPolicy: policy, Ports: ports}}
+	}
+
+	http := apiv1.ServicePort{Name: "http", Port: 80, Protocol: apiv1.ProtocolTCP}
+	unnamed := apiv1.ServicePort{Port: 53, Protocol: apiv1.ProtocolUDP}
+	withNodePort := func(p apiv1.ServicePort, nodePort int32) apiv1.ServicePort {
+		p.NodePort = nodePort
+		return p
+	}
+
+	existing := service(apiv1.ServiceTypeLoadBalancer, apiv1.ServiceExternalTrafficPolicyTypeLocal,
+		withNodePort(http, 30080), withNodePort(unnamed, 30053))
+	existing.Spec.ClusterIP = "10.0.0.1"
+	existing.Spec.HealthCheckNodePort = 32000
+
+	tests := []struct {
+		name        string
+		desired     *apiv1.Service
+		nodePorts   []int32
+		healthCheck int32
+	}{
+		{"cluster IP", service(apiv1.ServiceTypeClusterIP, "", http), []int32{0}, 0},
+		{"node port", service(apiv1.ServiceTypeNodePort, "", http), []int32{30080}, 0},
+		{"requested node port", service(apiv1.ServiceTypeNodePort, "", withNodePort(http, 31000)), []int32{31000}, 0},
+		{"unnamed port", service(apiv1.ServiceTypeNodePort, "", unnamed), []int32{30053}, 0},
+		{"unnamed port with another protocol", service(apiv1.ServiceTypeNodePort, "", apiv1.ServicePort{Port: 53, Protocol: apiv1.ProtocolTCP}), []int32{0}, 0},
+		{"renamed port", service(apiv1.ServiceTypeNodePort, "", apiv1.ServicePort{Name: "web", Port: 80, Protocol: apiv1.ProtocolTCP}), []int32{0}, 0},
+		{"load balancer", service(apiv1.ServiceTypeLoadBalancer, apiv1.ServiceExternalTrafficPolicyTypeCluster, http, unnamed), []int32{30080, 30053}, 0},
+		{"local traffic policy", service(apiv1.ServiceTypeLoadBalancer, apiv1.ServiceExternalTrafficPolicyTypeLocal, http), []int32{30080}, 32000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preserveServiceAllocations(tt.desired, existing)
+
+			if tt.desired.Spec.ClusterIP != existing.Spec.ClusterIP {
+				t.Errorf("cluster IP = %q, want %q", tt.desired.Spec.ClusterIP, existing.Spec.ClusterIP)
+			}
+
+			for i, p := range tt.desired.Spec.Ports {
+				if p.NodePort != tt.nodePorts[i] {
+					t.Errorf("node port of %s/%d = %d, want %d", p.Name, p.Port, p.NodePort, tt.nodePorts[i])
+				}
+			}
+
+			if tt.desired.Spec.HealthCheckNodePort != tt.healthCheck {
+				t.Errorf("health check node port = %d, want %d", tt.desired.Spec.HealthCheckNodePort, tt.healthCheck)
+			}
+		})
+	}
+}
+
+func TestDeploymentSelectorRecreate(t *testing.T) {
+	deployment := func(selector map[string]string) *appsv1.Deployment {
+		return &appsv1.Deployment{Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: selector},
+		}}
+	}
+
+	application := &model.Application{ID: "app"}
+
+	tests := []struct {
+		name     string
+		desired  map[string]string
+		existing map[string]string
+		want     bool
+	}{
+		{"same selector", getReplicaSelector(application, "s", 0), getReplicaSelector(application, "s", 0), false},
+		{"selector of a single instance", getReplicaSelector(application, "s", 0), getSelector(application, "s"), true},
+		{"other replica", getReplicaSelector(application, "s", 1), getReplicaSelector(application, "s", 0), true},
+		{"other application", getSelector(application, "s"), map[string]string{config.AppLabel: "other", config.ServiceLabel: "s"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := deploymentSelectorChanged(deployment(tt.desired), deployment(tt.existing))
+			if got != tt.want {
+				t.Errorf("selector changed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
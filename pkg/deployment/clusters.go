@@ -0,0 +1,124 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"fmt"
+	"foglute/internal/model"
+	"foglute/pkg/infrastructure"
+	"k8s.io/client-go/kubernetes"
+)
+
+// A cluster is a Kubernetes cluster whose nodes are part of the fog infrastructure
+type cluster struct {
+	// Name of the kubeconfig context of the cluster. It is empty if FogLute manages a single cluster
+	name string
+
+	clientset   *kubernetes.Clientset
+	nodeWatcher *infrastructure.NodeWatcher
+}
+
+// Returns the cluster with the given name
+func (manager *Manager) getCluster(name string) (*cluster, bool) {
+	for _, c := range manager.clusters {
+		if c.name == name {
+			return c, true
+		}
+	}
+
+	return nil, false
+}
+
+// Returns a copy of the manager whose Kubernetes operations target a cluster
+func (manager *Manager) withCluster(c *cluster) *Manager {
+	view := *manager
+	view.clientset = c.clientset
+
+	return &view
+}
+
+// Returns a copy of the manager whose Kubernetes operations target the cluster with the given name
+func (manager *Manager) onCluster(name string) (*Manager, error) {
+	c, exists := manager.getCluster(name)
+	if !exists {
+		return nil, fmt.Errorf("unknown cluster %s", name)
+	}
+
+	return manager.withCluster(c), nil
+}
+
+// The objects of a placement applied to a cluster
+type clusterObjects struct {
+	// Manager whose operations target the cluster
+	manager *Manager
+
+	cluster   string
+	namespace string
+	objects   *applicationObjects
+	created   *createdObjects
+}
+
+// Returns the assignments of a placement on the nodes of a cluster
+func getClusterPlacement(placement *model.Placement, clusterName string) *model.Placement {
+	clusterPlacement := *placement
+	clusterPlacement.Assignments = make([]model.Assignment, 0, len(placement.Assignments))
+
+	for _, a := range placement.Assignments {
+		if a.Cluster == clusterName {
+			clusterPlacement.Assignments = append(clusterPlacement.Assignments, a)
+		}
+	}
+
+	return &clusterPlacement
+}
+
+// Returns true if a replica is the first replica of its service in a placement.
+// It creates the Services shared by the replicas of the service in its cluster.
+func isFirstReplica(placement *model.Placement, assignment *model.Assignment) bool {
+	for _, a := range placement.Assignments {
+		if a.ServiceID == assignment.ServiceID && a.Replica < assignment.Replica {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Checks that every cluster hosting replicas of the source of a flow also hosts a replica of its destination.
+// Services are not reachable across clusters through their Kubernetes Services.
+// The placement assigns the replicas of the application, whose references are given, to nodes of the given clusters.
+func checkClusterFlows(application *model.Application, placement *model.Placement, refs map[string]replicaRef, nodeClusters map[string]string) error {
+	clusters := make(map[string]map[string]bool)
+	for _, a := range placement.Assignments {
+		serviceID := a.ServiceID
+		if ref, exists := refs[a.ServiceID]; exists {
+			serviceID = ref.serviceID
+		}
+
+		if clusters[serviceID] == nil {
+			clusters[serviceID] = make(map[string]bool)
+		}
+		clusters[serviceID][nodeClusters[a.NodeName]] = true
+	}
+
+	for _, f := range application.Flows {
+		dst, placed := clusters[f.Dst]
+		if !placed {
+			continue
+		}
+
+		for c := range clusters[f.Src] {
+			if !dst[c] {
+				return fmt.Errorf("service %s in cluster %s cannot reach service %s, which has no replica in that cluster", f.Src, c, f.Dst)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"foglute/internal/model"
+	"testing"
+)
+
+func TestCheckClusterFlows(t *testing.T) {
+	application := &model.Application{
+		ID: "app",
+		Services: []model.Service{
+			{Id: "web", Replicas: 2},
+			{Id: "db"},
+		},
+		Flows: []model.Flow{
+			{Src: "web", Dst: "db"},
+			{Src: "web", Dst: "external"},
+		},
+	}
+
+	_, refs, err := expandReplicas(application)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodeClusters := map[string]string{"a1": "site-a", "a2": "site-a", "b1": "site-b"}
+
+	placement := func(web0 string, web1 string, db string) *model.Placement {
+		return &model.Placement{Assignments: []model.Assignment{
+			{ServiceID: getReplicaID("web", 0), NodeName: web0},
+			{ServiceID: getReplicaID("web", 1), NodeName: web1},
+			{ServiceID: "db", NodeName: db},
+		}}
+	}
+
+	tests := []struct {
+		name      string
+		placement *model.Placement
+		fails     bool
+	}{
+		{"same cluster", placement("a1", "a2", "a2"), false},
+		{"destination in another cluster", placement("b1", "b1", "a1"), true},
+		{"a source replica in another cluster", placement("a1", "b1", "a2"), true},
+		{"destination only reached within its cluster", placement("b1", "b1", "b1"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkClusterFlows(application, tt.placement, refs, nodeClusters)
+			if (err != nil) != tt.fails {
+				t.Errorf("error = %v, want error %v", err, tt.fails)
+			}
+		})
+	}
+}
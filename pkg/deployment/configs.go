@@ -0,0 +1,174 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"foglute/internal/model"
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// Returns the name of the ConfigMap that stores a config of an application
+func getConfigMapName(application *model.Application, config *model.ConfigSource) string {
+	if config.Existing != "" {
+		return config.Existing
+	}
+
+	return makeName(false, application.ID, "config", config.Name)
+}
+
+// Returns the name of the Secret that stores a secret of an application
+func getSecretName(application *model.Application, secret *model.ConfigSource) string {
+	if secret.Existing != "" {
+		return secret.Existing
+	}
+
+	return makeName(false, application.ID, "secret", secret.Name)
+}
+
+// Returns the ConfigMaps to create for the configs of an application.
+// Existing ConfigMaps are not managed by FogLute.
+func getConfigMaps(application *model.Application) []*apiv1.ConfigMap {
+	configMaps := make([]*apiv1.ConfigMap, 0, len(application.Configs))
+
+	for i := range application.Configs {
+		c := &application.Configs[i]
+		if c.Existing != "" {
+			continue
+		}
+
+		configMaps = append(configMaps, &apiv1.ConfigMap{
+			ObjectMeta: getApplicationObjectMeta(application, getConfigMapName(application, c), c.Name),
+			Data:       c.Data,
+		})
+	}
+
+	return configMaps
+}
+
+// Returns the Secrets to create for the secrets of an application.
+// Existing Secrets are not managed by FogLute.
+func getSecrets(application *model.Application) []*apiv1.Secret {
+	secrets := make([]*apiv1.Secret, 0, len(application.Secrets))
+
+	for i := range application.Secrets {
+		s := &application.Secrets[i]
+		if s.Existing != "" {
+			continue
+		}
+
+		data := make(map[string][]byte, len(s.Data))
+		for key, value := range s.Data {
+			data[key] = []byte(value)
+		}
+
+		secrets = append(secrets, &apiv1.Secret{
+			ObjectMeta: getApplicationObjectMeta(application, getSecretName(application, s), s.Name),
+			Type:       apiv1.SecretTypeOpaque,
+			Data:       data,
+		})
+	}
+
+	return secrets
+}
+
+// Returns the volumes of the configs and the secrets mounted by the containers of a service,
+// and the volume mounts of each container
+func getConfigVolumes(application *model.Application, service *model.Service) ([]apiv1.Volume, [][]apiv1.VolumeMount) {
+	volumes := make([]apiv1.Volume, 0)
+	mounts := make([][]apiv1.VolumeMount, len(service.Images))
+	volumeNames := make(map[string]string)
+
+	for i, image := range service.Images {
+		for _, m := range image.Mounts {
+			var source apiv1.VolumeSource
+			var key string
+
+			if m.Config != "" {
+				c, _ := application.GetConfig(m.Config)
+				key = "config-" + m.Config
+				source.ConfigMap = &apiv1.ConfigMapVolumeSource{
+					LocalObjectReference: apiv1.LocalObjectReference{Name: getConfigMapName(application, c)},
+				}
+			} else {
+				s, _ := application.GetSecret(m.Secret)
+				key = "secret-" + m.Secret
+				source.Secret = &apiv1.SecretVolumeSource{
+					SecretName: getSecretName(application, s),
+				}
+			}
+
+			// Containers mounting the same source share its volume
+			name, exists := volumeNames[key]
+			if !exists {
+				name = makeName(false, key)
+				volumeNames[key] = name
+				volumes = append(volumes, apiv1.Volume{
+					Name:         name,
+					VolumeSource: source,
+				})
+			}
+
+			mounts[i] = append(mounts[i], apiv1.VolumeMount{
+				Name:      name,
+				MountPath: m.Path,
+				ReadOnly:  true,
+			})
+		}
+	}
+
+	return volumes, mounts
+}
+
+// Returns the environment variables of a container that come from configs and secrets
+func getConfigEnv(application *model.Application, image model.Image) ([]apiv1.EnvVar, []apiv1.EnvFromSource) {
+	env := make([]apiv1.EnvVar, 0)
+	envFrom := make([]apiv1.EnvFromSource, 0)
+
+	for _, e := range image.EnvFrom {
+		if e.Key == "" {
+			source := apiv1.EnvFromSource{}
+			if e.Config != "" {
+				c, _ := application.GetConfig(e.Config)
+				source.ConfigMapRef = &apiv1.ConfigMapEnvSource{
+					LocalObjectReference: apiv1.LocalObjectReference{Name: getConfigMapName(application, c)},
+				}
+			} else {
+				s, _ := application.GetSecret(e.Secret)
+				source.SecretRef = &apiv1.SecretEnvSource{
+					LocalObjectReference: apiv1.LocalObjectReference{Name: getSecretName(application, s)},
+				}
+			}
+
+			envFrom = append(envFrom, source)
+			continue
+		}
+
+		source := &apiv1.EnvVarSource{}
+		if e.Config != "" {
+			c, _ := application.GetConfig(e.Config)
+			source.ConfigMapKeyRef = &apiv1.ConfigMapKeySelector{
+				LocalObjectReference: apiv1.LocalObjectReference{Name: getConfigMapName(application, c)},
+				Key:                  e.Key,
+			}
+		} else {
+			s, _ := application.GetSecret(e.Secret)
+			source.SecretKeyRef = &apiv1.SecretKeySelector{
+				LocalObjectReference: apiv1.LocalObjectReference{Name: getSecretName(application, s)},
+				Key:                  e.Key,
+			}
+		}
+
+		env = append(env, apiv1.EnvVar{
+			Name:      e.Name,
+			ValueFrom: source,
+		})
+	}
+
+	return env, envFrom
+}
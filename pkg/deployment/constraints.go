@@ -0,0 +1,57 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"fmt"
+	"foglute/internal/model"
+)
+
+// Returns a new infrastructure that contains only the nodes that can host at least one service of the application.
+// An error is returned if a service cannot be placed on any node.
+func filterInfrastructure(application *model.Application, infrastructure *model.Infrastructure) (*model.Infrastructure, error) {
+	eligible := make(map[string]bool)
+
+	for i := range application.Services {
+		s := &application.Services[i]
+
+		found := false
+		for j := range infrastructure.Nodes {
+			n := &infrastructure.Nodes[j]
+
+			if s.CanRunOn(n) {
+				eligible[n.Name] = true
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("no nodes satisfy the constraints of service %s", s.Id)
+		}
+	}
+
+	filtered := &model.Infrastructure{
+		Nodes: make([]model.Node, 0),
+		Links: make([]model.Link, 0),
+	}
+
+	for _, n := range infrastructure.Nodes {
+		if eligible[n.Name] {
+			filtered.Nodes = append(filtered.Nodes, n)
+		}
+	}
+
+	for _, l := range infrastructure.Links {
+		if eligible[l.Src] && eligible[l.Dst] {
+			filtered.Links = append(filtered.Links, l)
+		}
+	}
+
+	return filtered, nil
+}
@@ -0,0 +1,95 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"foglute/internal/model"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestFilterInfrastructure(t *testing.T) {
+	infrastructure := &model.Infrastructure{
+		Nodes: []model.Node{
+			{Name: "a", Location: model.Location{Zone: "north"}},
+			{Name: "b", Location: model.Location{Zone: "north"}},
+			{Name: "c", Location: model.Location{Zone: "south"}},
+		},
+		Links: []model.Link{
+			{Src: "a", Dst: "b"},
+			{Src: "b", Dst: "a"},
+			{Src: "a", Dst: "c"},
+			{Src: "c", Dst: "a"},
+			{Src: "b", Dst: "c"},
+		},
+	}
+
+	inZone := func(zone string) []model.LocationConstraint {
+		return []model.LocationConstraint{{Type: model.InZoneConstraint, Zone: zone}}
+	}
+
+	tests := []struct {
+		name     string
+		services []model.Service
+		nodes    []string
+		links    []string
+		fails    bool
+	}{
+		{"no constraints", []model.Service{{Id: "s"}},
+			[]string{"a", "b", "c"}, []string{"a-b", "a-c", "b-a", "b-c", "c-a"}, false},
+		{"one eligible zone", []model.Service{{Id: "s", LocationConstraints: inZone("north")}},
+			[]string{"a", "b"}, []string{"a-b", "b-a"}, false},
+		{"nodes of any service", []model.Service{{Id: "s", NodeName: "a"}, {Id: "t", NodeName: "c"}},
+			[]string{"a", "c"}, []string{"a-c", "c-a"}, false},
+		{"excluded nodes", []model.Service{{Id: "s", ExcludedNodes: []string{"a", "b"}}},
+			[]string{"c"}, []string{}, false},
+		{"service without nodes", []model.Service{{Id: "s"}, {Id: "t", LocationConstraints: inZone("east")}},
+			nil, nil, true},
+		{"no services", []model.Service{},
+			[]string{}, []string{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			application := &model.Application{ID: "app", Services: tt.services}
+
+			filtered, err := filterInfrastructure(application, infrastructure)
+			if tt.fails {
+				if err == nil {
+					t.Fatalf("no error, want one")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			nodes := make([]string, 0)
+			for _, n := range filtered.Nodes {
+				nodes = append(nodes, n.Name)
+			}
+
+			links := make([]string, 0)
+			for _, l := range filtered.Links {
+				links = append(links, l.Src+"-"+l.Dst)
+			}
+			sort.Strings(links)
+
+			if !reflect.DeepEqual(nodes, tt.nodes) {
+				t.Errorf("nodes = %v, want %v", nodes, tt.nodes)
+			}
+
+			if !reflect.DeepEqual(links, tt.links) {
+				t.Errorf("links = %v, want %v", links, tt.links)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"foglute/internal/model"
+	"foglute/pkg/config"
+	apiv1 "k8s.io/api/core/v1"
+	"strconv"
+	"strings"
+)
+
+// Returns the name of the Kubernetes Service that exposes a group of ports of a single replica of a service
+func getReplicaServiceName(application *model.Application, serviceID string, replica int, group string) string {
+	return makeName(true, application.ID, serviceID, group, "replica", strconv.Itoa(replica))
+}
+
+// Returns a valid environment variable name for a string
+func getEnvName(s string) string {
+	name := strings.Map(func(c rune) rune {
+		if (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			return c
+		}
+		return '_'
+	}, strings.ToUpper(s))
+
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+
+	return name
+}
+
+// Returns the exposed ports of a service
+func getExposedPorts(service *model.Service) []model.Port {
+	ports := make([]model.Port, 0)
+	for _, image := range service.Images {
+		for _, port := range image.Ports {
+			if port.IsExposed() {
+				ports = append(ports, port)
+			}
+		}
+	}
+
+	return ports
+}
+
+// Returns true if a flow towards the service prefers the replica placed on the node of its source
+func hasLocalFlows(application *model.Application, service *model.Service) bool {
+	if service.GetReplicas() < 2 {
+		return false
+	}
+
+	for _, f := range application.Flows {
+		if f.Dst == service.Id && f.PreferLocal {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the Kubernetes Services that expose the ports of a single replica of a service.
+// Sources of flows that prefer local traffic reach the replica on their node through them.
+func createReplicaServices(application *model.Application, service *model.Service, replica int) []*apiv1.Service {
+	services := createServices(application, service)
+
+	for _, s := range services {
+		group := s.Annotations[config.OriginalNameAnnotation]
+
+		s.ObjectMeta = getObjectMeta(application, service.Id, getReplicaServiceName(application, service.Id, replica, group), group)
+		s.Spec.Selector = getReplicaSelector(application, service.Id, replica)
+		s.Spec.Type = apiv1.ServiceTypeClusterIP
+		for i := range s.Spec.Ports {
+			s.Spec.Ports[i].NodePort = 0
+		}
+	}
+
+	return services
+}
+
+// Returns the environment variables that let a replica of a service reach the destinations of its flows.
+// For each destination, <PREFIX>_HOST and <PREFIX>_PORT address its first exposed port, and
+// <PREFIX>_<PORT NAME>_HOST and <PREFIX>_<PORT NAME>_PORT each exposed port if there are many.
+// The prefix is the env of the flow or the id of the destination. Variables set by the image are not overridden.
+func getDiscoveryEnv(application *model.Application, service *model.Service, assignment *model.Assignment, placement *model.Placement, image model.Image) []apiv1.EnvVar {
+	env := make([]apiv1.EnvVar, 0)
+	set := func(name string, value string) {
+		if _, exists := image.Env[name]; !exists {
+			env = append(env, apiv1.EnvVar{Name: name, Value: value})
+		}
+	}
+
+	for _, f := range application.Flows {
+		if f.Src != service.Id {
+			continue
+		}
+
+		dst, exists := application.GetService(f.Dst)
+		if !exists {
+			continue
+		}
+
+		ports := getExposedPorts(dst)
+		if len(ports) == 0 {
+			continue
+		}
+
+		// Prefer the replica of the destination placed on the same node
+		local := -1
+		if f.PreferLocal && dst.GetReplicas() > 1 {
+			for _, a := range placement.Assignments {
+				if a.ServiceID == dst.Id && a.NodeName == assignment.NodeName {
+					local = a.Replica
+					break
+				}
+			}
+		}
+
+		host := func(port model.Port) string {
+			if local >= 0 {
+				return getReplicaServiceName(application, dst.Id, local, port.GetServiceName())
+			}
+
+			return getServiceName(application, dst.Id, port.GetServiceName())
+		}
+
+		prefix := f.Env
+		if prefix == "" {
+			prefix = getEnvName(dst.Id)
+		}
+
+		set(prefix+"_HOST", host(ports[0]))
+		set(prefix+"_PORT", strconv.Itoa(ports[0].GetServicePort()))
+
+		if len(ports) > 1 {
+			for _, port := range ports {
+				portPrefix := prefix + "_" + getEnvName(port.Name)
+				set(portPrefix+"_HOST", host(port))
+				set(portPrefix+"_PORT", strconv.Itoa(port.GetServicePort()))
+			}
+		}
+	}
+
+	return env
+}
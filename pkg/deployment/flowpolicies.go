@@ -0,0 +1,116 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"foglute/internal/model"
+	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Returns the name of the NetworkPolicy that restricts the traffic of a service to its flows
+func getFlowPolicyName(application *model.Application, serviceID string) string {
+	return makeName(false, application.ID, serviceID, "flows")
+}
+
+// Returns the container ports of a service.
+// An empty list matches every port, so that services without declared ports can still be reached.
+func getPolicyPorts(service *model.Service) []networkingv1.NetworkPolicyPort {
+	ports := make([]networkingv1.NetworkPolicyPort, 0)
+	for _, image := range service.Images {
+		for _, p := range image.Ports {
+			protocol := apiv1.Protocol(p.GetProtocol())
+			port := intstr.FromInt(p.ContainerPort)
+			ports = append(ports, networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &port})
+		}
+	}
+
+	return ports
+}
+
+// Returns true if the traffic of the application is restricted to its flows
+func (manager *Manager) hasFlowPolicies(application *model.Application) bool {
+	return application.FlowPolicies || manager.config.FlowPolicies
+}
+
+// Returns the NetworkPolicies that allow only the declared flows of an application.
+// The pods of each service accept connections from the sources of its flows and, on exposed and routed ports,
+// from outside the application; they open connections to the destinations of its flows and to DNS servers.
+func getFlowPolicies(application *model.Application) []*networkingv1.NetworkPolicy {
+	policies := make([]*networkingv1.NetworkPolicy, 0, len(application.Services))
+
+	for i := range application.Services {
+		s := &application.Services[i]
+
+		policy := &networkingv1.NetworkPolicy{
+			ObjectMeta: getObjectMeta(application, s.Id, getFlowPolicyName(application, s.Id), ""),
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: getSelector(application, s.Id)},
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+				Ingress:     []networkingv1.NetworkPolicyIngressRule{},
+				Egress: []networkingv1.NetworkPolicyEgressRule{
+					{Ports: getDNSPorts()},
+				},
+			},
+		}
+
+		sources := make(map[string]bool)
+		destinations := make(map[string]bool)
+
+		for _, f := range application.Flows {
+			if f.Dst == s.Id && !sources[f.Src] {
+				sources[f.Src] = true
+				policy.Spec.Ingress = append(policy.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{
+					From: []networkingv1.NetworkPolicyPeer{
+						{PodSelector: &metav1.LabelSelector{MatchLabels: getSelector(application, f.Src)}},
+					},
+					Ports: getPolicyPorts(s),
+				})
+			}
+
+			if f.Src == s.Id && !destinations[f.Dst] {
+				destinations[f.Dst] = true
+				dst, _ := application.GetService(f.Dst)
+				policy.Spec.Egress = append(policy.Spec.Egress, networkingv1.NetworkPolicyEgressRule{
+					To: []networkingv1.NetworkPolicyPeer{
+						{PodSelector: &metav1.LabelSelector{MatchLabels: getSelector(application, f.Dst)}},
+					},
+					Ports: getPolicyPorts(dst),
+				})
+			}
+		}
+
+		if ports := getPublicPorts(application, s); len(ports) > 0 {
+			policy.Spec.Ingress = append(policy.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{Ports: ports})
+		}
+
+		policies = append(policies, policy)
+	}
+
+	return policies
+}
+
+// Returns the NetworkPolicies generated for an application: the ones restricting its flows, if enabled,
+// and the ones isolating the services with security requirements.
+// NetworkPolicies are additive, so isolation policies allow the traffic within the application only without flow
+// policies; otherwise they would allow again every path between the pods of the application.
+func (manager *Manager) getNetworkPolicies(application *model.Application) []*networkingv1.NetworkPolicy {
+	if !manager.hasFlowPolicies(application) {
+		return getIsolationPolicies(application, manager.config.SecurityMappings, true)
+	}
+
+	return append(getFlowPolicies(application), getIsolationPolicies(application, manager.config.SecurityMappings, false)...)
+}
+
+// Returns the NetworkPolicies that a deploy of the application would apply, without deploying it
+func (manager *Manager) PreviewNetworkPolicies(application *model.Application) []*networkingv1.NetworkPolicy {
+	return manager.getNetworkPolicies(application)
+}
@@ -0,0 +1,202 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"fmt"
+	"foglute/internal/model"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sort"
+)
+
+const (
+	// Annotation that selects the ingress controller that serves an Ingress
+	ingressClassAnnotation = "kubernetes.io/ingress.class"
+)
+
+// Returns the name of the Ingress of an application
+func getIngressName(application *model.Application) string {
+	return makeName(false, application.ID, "ingress")
+}
+
+// Returns the name of the Secret with the TLS certificate of a route
+func getTLSSecretName(application *model.Application, route model.Route) string {
+	if secret, exists := application.GetSecret(route.TLSSecret); exists {
+		return getSecretName(application, secret)
+	}
+
+	return route.TLSSecret
+}
+
+// Returns the Ingresses that route HTTP requests to the services of an application placed by a placement.
+// All the routes of an application are served by a single Ingress.
+func getIngresses(application *model.Application, placement *model.Placement, ingressClass string) []*networkingv1beta1.Ingress {
+	placed := make(map[string]bool)
+	for _, a := range placement.Assignments {
+		placed[a.ServiceID] = true
+	}
+
+	routes := make([]model.Route, 0, len(application.Routes))
+	for _, r := range application.Routes {
+		if placed[r.Service] {
+			routes = append(routes, r)
+		}
+	}
+
+	if len(routes) == 0 {
+		return []*networkingv1beta1.Ingress{}
+	}
+
+	ingress := &networkingv1beta1.Ingress{
+		ObjectMeta: getApplicationObjectMeta(application, getIngressName(application), ""),
+	}
+
+	if ingressClass != "" {
+		ingress.Annotations[ingressClassAnnotation] = ingressClass
+	}
+
+	// Rules are grouped by host, in the order in which hosts first appear
+	rules := make(map[string]*networkingv1beta1.IngressRule)
+	hosts := make([]string, 0)
+
+	// Hosts are grouped by TLS secret
+	tlsHosts := make(map[string][]string)
+	secured := make(map[string]bool)
+
+	for _, r := range routes {
+		service, _ := application.GetService(r.Service)
+		port, _ := service.GetPort(r.Port)
+
+		rule, exists := rules[r.Host]
+		if !exists {
+			rule = &networkingv1beta1.IngressRule{
+				Host: r.Host,
+				IngressRuleValue: networkingv1beta1.IngressRuleValue{
+					HTTP: &networkingv1beta1.HTTPIngressRuleValue{},
+				},
+			}
+			rules[r.Host] = rule
+			hosts = append(hosts, r.Host)
+		}
+
+		rule.HTTP.Paths = append(rule.HTTP.Paths, networkingv1beta1.HTTPIngressPath{
+			Path: r.GetPath(),
+			Backend: networkingv1beta1.IngressBackend{
+				ServiceName: getServiceName(application, r.Service, port.GetServiceName()),
+				ServicePort: intstr.FromInt(port.GetServicePort()),
+			},
+		})
+
+		if r.TLSSecret != "" && !secured[r.Host] {
+			secret := getTLSSecretName(application, r)
+			tlsHosts[secret] = append(tlsHosts[secret], r.Host)
+			secured[r.Host] = true
+		}
+	}
+
+	for _, host := range hosts {
+		ingress.Spec.Rules = append(ingress.Spec.Rules, *rules[host])
+	}
+
+	secrets := make([]string, 0, len(tlsHosts))
+	for secret := range tlsHosts {
+		secrets = append(secrets, secret)
+	}
+	sort.Strings(secrets)
+
+	for _, secret := range secrets {
+		ingress.Spec.TLS = append(ingress.Spec.TLS, networkingv1beta1.IngressTLS{
+			Hosts:      tlsHosts[secret],
+			SecretName: secret,
+		})
+	}
+
+	return []*networkingv1beta1.Ingress{ingress}
+}
+
+// Returns the nodes that run the ingress controller
+func (manager *Manager) getIngressNodes() ([]model.Node, error) {
+	selector, err := labels.Parse(manager.config.IngressNodeSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ingress node selector %s: %s", manager.config.IngressNodeSelector, err)
+	}
+
+	allNodes, err := manager.GetNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]model.Node, 0, len(allNodes))
+	for _, n := range allNodes {
+		if selector.Matches(labels.Set(n.Node.Labels)) {
+			nodes = append(nodes, n)
+		}
+	}
+
+	return nodes, nil
+}
+
+// Checks that every route of an application reaches at least one replica of its service.
+// A replica is reachable if it runs on an ingress node or on a node of the same cluster linked to an ingress node.
+func (manager *Manager) checkRoutes(application *model.Application, placement *model.Placement) error {
+	if len(application.Routes) == 0 {
+		return nil
+	}
+
+	ingressNodes, err := manager.getIngressNodes()
+	if err != nil {
+		return err
+	}
+
+	if len(ingressNodes) == 0 {
+		return fmt.Errorf("application %s has routes but no ingress node is available", application.ID)
+	}
+
+	allNodes, err := manager.GetNodes()
+	if err != nil {
+		return err
+	}
+
+	clusters := make(map[string]string)
+	for _, n := range allNodes {
+		clusters[n.Name] = n.Cluster
+	}
+
+	isIngressNode := make(map[string]bool)
+	reachable := make(map[string]bool)
+	for _, n := range ingressNodes {
+		isIngressNode[n.Name] = true
+		reachable[n.Name] = true
+	}
+
+	// Ingress controllers route requests only to the pods of their cluster
+	for _, l := range manager.linkNodes(allNodes).Links {
+		if isIngressNode[l.Src] && l.Probability > 0 && clusters[l.Src] == clusters[l.Dst] {
+			reachable[l.Dst] = true
+		}
+	}
+
+	for _, r := range application.Routes {
+		found := false
+		for _, a := range placement.Assignments {
+			if a.ServiceID == r.Service && reachable[a.NodeName] {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("route %s%s: service %s is not reachable from any ingress node", r.Host, r.GetPath(), r.Service)
+		}
+	}
+
+	return nil
+}
@@ -16,11 +16,12 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/utils/pointer"
 	"log"
-	"strings"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -34,6 +35,26 @@ const (
 type Deploy struct {
 	Application *model.Application `json:"application"`
 	Placement   *model.Placement   `json:"placement"`
+
+	// Results of the last operations on the Kubernetes objects of the application
+	Objects []ObjectResult `json:"objects"`
+
+	// Readiness of the services on their nodes
+	Status []ServiceStatus `json:"status"`
+
+	// Traffic limits of the services, if bandwidth shaping is enabled
+	Shaping []ServiceShaping `json:"shaping,omitempty"`
+
+	// Objects applied to each cluster, removed if the services do not become ready and the failure policy is rollback
+	applied []*clusterObjects
+}
+
+// Returns a copy of the deploy that can be exposed: secret values are redacted
+func (d *Deploy) Redacted() *Deploy {
+	redacted := *d
+	redacted.Application = d.Application.Redacted()
+
+	return &redacted
 }
 
 // The Deployer component is responsible to store information about applications that are deployed by FogLute,
@@ -42,26 +63,45 @@ type Manager struct {
 	// Analyzer to produce placements for deployments
 	analyzer *PlacementAnalyzer
 
-	// Kubernetes Clientset
+	// Kubernetes Clientset of the cluster targeted by the operations of the manager
 	clientset *kubernetes.Clientset
 
-	// NodeWatcher on Kubernetes nodes
-	nodeWatcher *infrastructure.NodeWatcher
+	// FogLute settings
+	config *config.Config
+
+	// Kubernetes clusters whose nodes make up the infrastructure
+	clusters []*cluster
 
 	// Deployed deployments
 	deployments []*Deploy
 
+	// Guards the deployments and the pending replacements. Locks are shared with the views on each cluster
+	mutex *sync.RWMutex
+
+	// Serializes the operations that change the applications on the clusters
+	operations *sync.Mutex
+
+	// Applications to place again because of changes of their nodes, and the signal that some are pending
+	pendingReplacements map[string]bool
+	replacements        chan struct{}
+
 	// Stop channels
 	quit chan struct{}
 	done chan struct{}
 }
 
 func (manager *Manager) GetDeployments() []*Deploy {
-	return manager.deployments
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+
+	return append([]*Deploy{}, manager.deployments...)
 }
 
 // Returns the application with the specified id handled by the manager.
 func (manager *Manager) GetDeployByApplicationID(id string) (*Deploy, bool) {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+
 	for _, dep := range manager.deployments {
 		if dep.Application.ID == id {
 			return dep, true
@@ -73,6 +113,9 @@ func (manager *Manager) GetDeployByApplicationID(id string) (*Deploy, bool) {
 
 // Returns true if the provided application is currently deployed by the manager
 func (manager *Manager) HasApplication(application *model.Application) bool {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+
 	for _, dep := range manager.deployments {
 		if application.ID == dep.Application.ID {
 			return true
@@ -84,22 +127,22 @@ func (manager *Manager) HasApplication(application *model.Application) bool {
 // Adds an application to the manager.
 // If the application is already deployed, nothing is done. Otherwise the application is started and added to the manager
 func (manager *Manager) AddApplication(application *model.Application) []error {
+	manager.operations.Lock()
+	defer manager.operations.Unlock()
+
 	if !manager.HasApplication(application) {
 		// Deploy the new application
-		placement, err := manager.deploy(application)
+		d, err := manager.deploy(application)
 
 		// Return if the deployment is not performed
-		if err != nil && placement == nil {
+		if err != nil && d == nil {
 			return err
 		}
 
-		d := &Deploy{
-			Application: application,
-			Placement:   placement,
-		}
-
 		log.Printf("Adding %s to manager's active deployments\n", application.ID)
+		manager.mutex.Lock()
 		manager.deployments = append(manager.deployments, d)
+		manager.mutex.Unlock()
 
 		// return errors if there are some
 		if err != nil {
@@ -113,6 +156,9 @@ func (manager *Manager) AddApplication(application *model.Application) []error {
 // Deletes an application from the manager.
 // If the application is deployed, then it removes the application from the cluster
 func (manager *Manager) DeleteApplication(application *model.Application) []error {
+	manager.operations.Lock()
+	defer manager.operations.Unlock()
+
 	if !manager.HasApplication(application) {
 		return []error{fmt.Errorf("cannot find application %s", application.Name)}
 	}
@@ -120,11 +166,14 @@ func (manager *Manager) DeleteApplication(application *model.Application) []erro
 	err := manager.delete(application)
 
 	// Remove app from the deployments list
+	manager.mutex.Lock()
 	for i, dep := range manager.deployments {
 		if dep.Application.ID == application.ID {
 			manager.deployments = append(manager.deployments[:i], manager.deployments[i+1:]...)
+			break
 		}
 	}
+	manager.mutex.Unlock()
 
 	if err != nil {
 		return err
@@ -137,18 +186,32 @@ func (manager *Manager) DeleteApplication(application *model.Application) []erro
 var instance *Manager
 
 // Get an instance of Manager
-func NewDeploymentManager(usher *PlacementAnalyzer, clientset *kubernetes.Clientset, quit chan struct{}) (*Manager, error) {
+func NewDeploymentManager(usher *PlacementAnalyzer, clusters []infrastructure.Cluster, cfg *config.Config, quit chan struct{}) (*Manager, error) {
+	if len(clusters) == 0 {
+		return nil, fmt.Errorf("no cluster to manage")
+	}
+
 	if instance == nil {
 		instance = &Manager{
 			analyzer:    usher,
-			clientset:   clientset,
+			clientset:   clusters[0].Clientset,
+			config:      cfg,
 			deployments: make([]*Deploy, 0),
-			nodeWatcher: nil,
+			clusters:    make([]*cluster, len(clusters)),
+
+			mutex:               &sync.RWMutex{},
+			operations:          &sync.Mutex{},
+			pendingReplacements: make(map[string]bool),
+			replacements:        make(chan struct{}, 1),
 
 			quit: quit,
 			done: make(chan struct{}),
 		}
 
+		for i, c := range clusters {
+			instance.clusters[i] = &cluster{name: c.Name, clientset: c.Clientset}
+		}
+
 		if err := instance.init(); err != nil {
 			return nil, err
 		}
@@ -163,76 +226,235 @@ func (manager *Manager) init() error {
 	log.Println("Initializing Assignment manager")
 	// TODO: Check actual status of deployed deployments
 
-	// Start node watcher
-	w, err := infrastructure.NewNodeWatcher(manager.clientset)
-	if err != nil {
-		return err
+	// Start a node watcher on each cluster
+	for _, c := range manager.clusters {
+		w, err := infrastructure.NewNodeWatcher(c.clientset, manager.config.NodeSelector)
+		if err != nil {
+			return fmt.Errorf("cannot watch the nodes of cluster %s: %s", c.name, err)
+		}
+
+		c.nodeWatcher = w
+
+		events, cancel := w.Subscribe()
+		go manager.watchNodes(w, events, cancel)
 	}
 
-	instance.nodeWatcher = w
+	go manager.watchReplacements()
 
 	return nil
 }
 
-// Perform the redeploy of all deployments managed by the Manager
-func (manager *Manager) redeployAll() []error {
-	log.Printf("Redeploying deployments (%d) for new node configuration\n", len(manager.deployments))
-
-	startTime := time.Now()
+// Handles changes of the nodes of a cluster until the manager is stopped
+func (manager *Manager) watchNodes(watcher *infrastructure.NodeWatcher, events <-chan infrastructure.NodeEvent, cancel func()) {
+	defer cancel()
+
+	for {
+		select {
+		case event := <-events:
+			manager.handleNodeEvent(event)
+		case <-manager.quit:
+			watcher.Stop()
+			return
+		}
+	}
+}
 
-	var errs []error
+// Schedules a new placement of the applications affected by a node change.
+// Applications with services on a node that is no longer available, or whose labels or taints changed, are placed again.
+func (manager *Manager) handleNodeEvent(event infrastructure.NodeEvent) {
+	if event.Schedulable && !event.LabelsChanged && !event.TaintsChanged {
+		return
+	}
 
-	// recompute all deployments
-	errors := make(chan error)
-	var wg sync.WaitGroup
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
 
+	affected := false
 	for _, dep := range manager.deployments {
-		wg.Add(1)
+		if dep.Placement == nil {
+			continue
+		}
 
-		go func() {
-			defer wg.Done()
-			placement, deployErrors := manager.redeploy(dep.Application)
+		for _, a := range dep.Placement.Assignments {
+			if a.NodeName != event.Node.Name {
+				continue
+			}
 
-			// Update application's placement
-			dep.Placement = placement
+			if event.Schedulable {
+				log.Printf("Capabilities of node %s hosting service %s of application %s changed\n", a.NodeName, a.ServiceID, dep.Application.ID)
+			} else {
+				log.Printf("Warning: node %s hosting service %s of application %s is no longer available (%s)\n", a.NodeName, a.ServiceID, dep.Application.ID, event.Type)
+			}
 
-			if deployErrors != nil {
-				for _, err := range deployErrors {
-					errors <- err
-				}
+			manager.pendingReplacements[dep.Application.ID] = true
+			affected = true
+		}
+	}
+
+	if affected {
+		select {
+		case manager.replacements <- struct{}{}:
+		default:
+			// A replacement is already signaled
+		}
+	}
+}
+
+// Places again the applications affected by node changes until the manager is stopped.
+// Replacements run apart from the node watchers, so that node events are not dropped meanwhile.
+func (manager *Manager) watchReplacements() {
+	for {
+		select {
+		case <-manager.replacements:
+			manager.replacePending()
+		case <-manager.quit:
+			return
+		}
+	}
+}
+
+// Places again the applications whose replacement is pending
+func (manager *Manager) replacePending() {
+	manager.mutex.Lock()
+	ids := make([]string, 0, len(manager.pendingReplacements))
+	for id := range manager.pendingReplacements {
+		ids = append(ids, id)
+	}
+	manager.pendingReplacements = make(map[string]bool)
+	manager.mutex.Unlock()
+
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		manager.operations.Lock()
+
+		// The application may have been deleted meanwhile
+		if dep, exists := manager.GetDeployByApplicationID(id); exists {
+			log.Printf("Placing application %s again after a node change\n", id)
+
+			if redeployed, _ := manager.redeploy(dep.Application); redeployed != nil {
+				manager.replaceDeploy(redeployed)
 			}
-		}()
+		}
+
+		manager.operations.Unlock()
+	}
+}
+
+// Replaces the deploy of an application with a new one
+func (manager *Manager) replaceDeploy(d *Deploy) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	for i, dep := range manager.deployments {
+		if dep.Application.ID == d.Application.ID {
+			manager.deployments[i] = d
+			return
+		}
 	}
+}
+
+// Performs the deploy of an application and waits for its services to be ready.
+// Services that fail on their node are placed again excluding that node, up to the configured number of times.
+// If a new placement cannot be deployed, the previous deploy, whose objects are still applied, is returned.
+// Services that do not become ready are failures handled like apply errors: if the failure policy is rollback, the
+// objects created by every attempt are removed and no deploy is returned.
+func (manager *Manager) deploy(application *model.Application) (*Deploy, []error) {
+	excluded := make(map[string][]string)
+
+	var last *Deploy
+	var lastErrors []error
+
+	applied := make([]*clusterObjects, 0)
+
+	for attempt := 0; ; attempt++ {
+		d, errs := manager.deployExcluding(application, excluded)
+		if d == nil && last != nil {
+			return manager.failRollout(last, append(lastErrors, errs...), applied)
+		}
 
-	// Wait for goroutines to end
-	go func() {
-		log.Println("Waiting for redeployment finishes...")
-		wg.Wait()
+		if d == nil || manager.config.RolloutTimeout <= 0 {
+			return d, errs
+		}
+
+		applied = append(applied, d.applied...)
+
+		d.Status = manager.waitForRollout(application, d.Placement, manager.config.RolloutTimeout)
+
+		rolloutErrors := getRolloutErrors(d.Status)
+		if len(rolloutErrors) == 0 {
+			log.Printf("Application %s successfully deployed\n", application.ID)
+			return d, errs
+		}
 
-		elapsed := time.Since(startTime)
-		log.Printf("Redeploy took %v\n", elapsed)
+		replace := false
+		for _, s := range d.Status {
+			if !s.Failed() || attempt >= manager.config.MaxReplacements {
+				continue
+			}
 
-		close(errors)
-	}()
+			service, _ := application.GetService(s.ServiceID)
+			if service.HasPersistentVolumes() && !manager.config.ForceRelocation {
+				log.Printf("Service %s is not moved away from its data on %s\n", s.ServiceID, s.NodeName)
+				continue
+			}
 
-	// Collect errors
-	for err := range errors {
-		errs = append(errs, fmt.Errorf("an application cannot be redeployed: %s", err))
+			// The node of a pinned service is the only one it can run on
+			if service.NodeName != "" {
+				log.Printf("Service %s is pinned to node %s and is not placed again\n", s.ServiceID, s.NodeName)
+				continue
+			}
+
+			log.Printf("Placing service %s again excluding %s\n", s.ServiceID, s.NodeName)
+			excluded[s.ServiceID] = append(excluded[s.ServiceID], s.NodeName)
+			replace = true
+		}
+
+		if !replace {
+			return manager.failRollout(d, append(errs, rolloutErrors...), applied)
+		}
+
+		last, lastErrors = d, append(errs, rolloutErrors...)
 	}
+}
 
-	log.Printf("Redeployment finishes with %d errors\n", len(errs))
+// Handles a deploy whose services did not become ready.
+// If the failure policy is rollback, the objects created in every cluster by the attempts of the deploy are removed,
+// the latest first, and no deploy is returned. Otherwise the deploy is returned with its objects left on the clusters.
+func (manager *Manager) failRollout(d *Deploy, errs []error, applied []*clusterObjects) (*Deploy, []error) {
+	if manager.config.FailurePolicy != config.RollbackOnFailure {
+		log.Printf("Application %s partially deployed\n", d.Application.ID)
+		return d, errs
+	}
 
-	return errs
+	for i := len(applied) - 1; i >= 0; i-- {
+		a := applied[i]
+		for _, err := range a.manager.rollback(d.Application, a.namespace, a.created) {
+			if a.cluster != "" {
+				err = fmt.Errorf("cluster %s: %s", a.cluster, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	log.Printf("Application %s not deployed\n", d.Application.ID)
+
+	return nil, errs
 }
 
 // Performs the deploy of an application
-// It gets the current state of the Kubernetes cluster and produce a feasible placement for the application
-func (manager *Manager) deploy(application *model.Application) (*model.Placement, []error) {
+// It gets the current state of the Kubernetes cluster and produce a feasible placement for the application.
+// Services are not placed on the nodes they are excluded from.
+func (manager *Manager) deployExcluding(application *model.Application, excluded map[string][]string) (*Deploy, []error) {
 	log.Printf("Call to deploy with app: %s (%s)\n", application.ID, application.Name)
 
 	startTime := time.Now()
 
-	currentInfrastructure, err := manager.getInfrastructure()
+	if err := manager.CheckSecurityPolicy(application); err != nil {
+		return nil, []error{fmt.Errorf("cannot deploy app %s: %s", application.ID, err)}
+	}
+
+	currentInfrastructure, err := manager.getInfrastructure(application)
 	if err != nil {
 		return nil, []error{err}
 	}
@@ -242,116 +464,310 @@ func (manager *Manager) deploy(application *model.Application) (*model.Placement
 		log.Printf("(%s) %s\n", n.ID, n.Name)
 	}
 
-	log.Printf("Getting a deployment for app %s (%s)\n", application.Name, application.ID)
+	// Express structured service requirements in HW units
+	preparedApp := prepareApplication(application, manager.config.ResourceWeights, toResources(manager.config.HWUnit))
 
-	placements, err := (*manager.analyzer).GetPlacements(Normal, application, currentInfrastructure)
+	// Each replica is placed as a distinct service
+	analysisApp, replicas, err := expandReplicas(preparedApp)
 	if err != nil {
-		return nil, []error{err}
+		return nil, []error{fmt.Errorf("cannot devise a placement for app %s: %s", application.ID, err)}
 	}
 
-	log.Printf("Devised %d possible placements\n", len(placements))
+	for i := range analysisApp.Services {
+		s := &analysisApp.Services[i]
+		ref := replicas[s.Id]
+		s.ExcludedNodes = excluded[ref.serviceID]
+
+		// Keep stateful services on the node that holds their data
+		if s.NodeName == "" && !manager.config.ForceRelocation {
+			service, _ := application.GetService(ref.serviceID)
+			if node := manager.getDataNode(application, service, ref.replica); node != "" {
+				log.Printf("Service %s stays on %s with its data\n", s.Id, node)
+				s.NodeName = node
+			}
+		}
+	}
 
-	best, err := pickBestPlacement(placements)
+	// Remove nodes that cannot host any service
+	eligibleInfrastructure, err := filterInfrastructure(analysisApp, currentInfrastructure)
 	if err != nil {
 		return nil, []error{fmt.Errorf("cannot devise a placement for app %s: %s", application.ID, err)}
 	}
 
-	// fixing ids
+	log.Printf("Eligible nodes: %d\n", len(eligibleInfrastructure.Nodes))
+
+	log.Printf("Getting a deployment for app %s (%s)\n", application.Name, application.ID)
+
+	placements, err := (*manager.analyzer).GetPlacements(Normal, analysisApp, eligibleInfrastructure)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	log.Printf("Devised %d possible placements\n", len(placements))
+
 	ids := map[string]string{}
+	clusters := map[string]string{}
 	for _, node := range currentInfrastructure.Nodes {
 		ids[node.Name] = node.ID
+		clusters[node.Name] = node.Cluster
+	}
+
+	// Discard placements that do not satisfy the affinity rules, whatever the analyzer, and, with many clusters,
+	// the placements of flows between clusters
+	feasible := make([]model.Placement, 0, len(placements))
+	for i := range placements {
+		if err := analysisApp.CheckAffinities(&placements[i]); err != nil {
+			log.Printf("Discarding placement %s: %s\n", placements[i], err)
+			continue
+		}
+
+		if len(manager.clusters) > 1 {
+			if err := checkClusterFlows(application, &placements[i], replicas, clusters); err != nil {
+				log.Printf("Discarding placement %s: %s\n", placements[i], err)
+				continue
+			}
+		}
+
+		feasible = append(feasible, placements[i])
+	}
+	placements = feasible
+
+	best, err := pickBestPlacement(placements)
+	if err != nil {
+		return nil, []error{fmt.Errorf("cannot devise a placement for app %s: %s", application.ID, err)}
 	}
 
+	// fixing ids
 	for i := range best.Assignments {
 		a := &best.Assignments[i]
 		if id, exists := ids[a.NodeName]; exists {
 			a.NodeID = id
+			a.Cluster = clusters[a.NodeName]
 		} else {
 			return nil, []error{fmt.Errorf("cannot find node id for %s", a.NodeName)}
 		}
 	}
 
+	if err := collapseReplicas(best, replicas); err != nil {
+		return nil, []error{err}
+	}
+
+	if err := manager.checkRoutes(application, best); err != nil {
+		return nil, []error{fmt.Errorf("cannot deploy app %s: %s", application.ID, err)}
+	}
+
 	log.Printf("Best placement: (P = %f)\n", best.Probability)
 	for _, a := range best.Assignments {
-		log.Printf("%s on (%s) %s\n", a.ServiceID, a.NodeID, a.NodeName)
+		log.Printf("%s (replica %d) on (%s) %s\n", a.ServiceID, a.Replica, a.NodeID, a.NodeName)
 	}
 
-	deployErrors := manager.performPlacement(application, currentInfrastructure, best)
+	applied, results, deployErrors := manager.performPlacement(application, currentInfrastructure, best)
 
 	elapsed := time.Since(startTime)
 	log.Printf("Deploy took %v\n", elapsed)
 
-	log.Printf("Application %s successfully deployed\n", application.ID)
+	for _, r := range results {
+		log.Printf("%s %s %s\n", r.Kind, r.Name, r.Action)
+	}
+
+	d := &Deploy{
+		Application: application,
+		Placement:   best,
+		Objects:     results,
+		applied:     applied,
+	}
+
+	if manager.config.BandwidthShaping {
+		d.Shaping = getShaping(application)
+	}
 
 	if len(deployErrors) > 0 {
-		return best, deployErrors
+		// Nothing created is left on the cluster after a rollback
+		if manager.config.FailurePolicy == config.RollbackOnFailure {
+			log.Printf("Application %s not deployed\n", application.ID)
+			return nil, deployErrors
+		}
+
+		log.Printf("Application %s partially deployed\n", application.ID)
+		return d, deployErrors
 	}
 
-	return best, nil
+	log.Printf("Application %s objects successfully applied\n", application.ID)
+
+	return d, nil
 }
 
-// Performs proper operations in order to apply the placement to the Kubernetes cluster
-func (manager *Manager) performPlacement(application *model.Application, infrastructure *model.Infrastructure, placement *model.Placement) []error {
-	log.Println("Performing placement")
+// Performs proper operations in order to apply the placement to the Kubernetes clusters.
+// Each cluster gets the objects of the services placed on its nodes. Once every cluster is applied, objects of the
+// application that are not needed anymore are deleted, also from the clusters that do not host any of its services.
+// If a cluster fails and the failure policy is rollback, the objects created in every cluster are deleted.
+// It returns the objects applied to each cluster.
+func (manager *Manager) performPlacement(application *model.Application, infrastructure *model.Infrastructure, placement *model.Placement) ([]*clusterObjects, []ObjectResult, []error) {
+	multiCluster := len(manager.clusters) > 1
 
+	results := make([]ObjectResult, 0)
 	errors := make([]error, 0)
 
-	deploymentsClient := manager.clientset.AppsV1().Deployments(apiv1.NamespaceDefault)
-	servicesClient := manager.clientset.CoreV1().Services(apiv1.NamespaceDefault)
+	addResults := func(clusterName string, clusterResults []ObjectResult, clusterErrors []error) {
+		for i := range clusterResults {
+			clusterResults[i].Cluster = clusterName
+		}
 
-	for _, assignment := range placement.Assignments {
-		deployment, services, err := manager.createDeploymentFromAssignment(application, infrastructure, &assignment)
-		if err != nil {
-			log.Printf("Cannot get Deployment and Services for application %s and assignment (%s, %s): %s\n", application.ID, assignment.ServiceID, assignment.NodeID, err)
+		results = append(results, clusterResults...)
+		for _, err := range clusterErrors {
+			if multiCluster {
+				err = fmt.Errorf("cluster %s: %s", clusterName, err)
+			}
 			errors = append(errors, err)
+		}
+	}
+
+	rollback := manager.config.FailurePolicy == config.RollbackOnFailure
+
+	applied := make([]*clusterObjects, 0, len(manager.clusters))
+	for _, c := range manager.clusters {
+		clusterPlacement := getClusterPlacement(placement, c.name)
+		if len(clusterPlacement.Assignments) == 0 {
 			continue
 		}
 
-		_, err = deploymentsClient.Create(deployment)
+		if multiCluster {
+			log.Printf("Performing placement on cluster %s\n", c.name)
+		}
+
+		objects, clusterResults, clusterErrors := manager.withCluster(c).applyClusterPlacement(application, infrastructure, clusterPlacement)
+		if objects != nil {
+			objects.cluster = c.name
+			applied = append(applied, objects)
+		}
+
+		addResults(c.name, clusterResults, clusterErrors)
+
+		// The remaining clusters would be rolled back anyway
+		if len(clusterErrors) > 0 && rollback {
+			break
+		}
+	}
+
+	if len(errors) > 0 {
+		if rollback {
+			for _, a := range applied {
+				addResults(a.cluster, nil, a.manager.rollback(application, a.namespace, a.created))
+			}
+		}
+
+		return applied, results, errors
+	}
+
+	for _, a := range applied {
+		pruned := a.manager.pruneObjects(application, a.namespace, a.objects)
+		addResults(a.cluster, pruned, getResultErrors(pruned))
+	}
+
+	for _, c := range manager.clusters {
+		if len(getClusterPlacement(placement, c.name).Assignments) == 0 {
+			clusterResults, clusterErrors := manager.withCluster(c).deleteFromCluster(application)
+			addResults(c.name, clusterResults, clusterErrors)
+		}
+	}
+
+	return applied, results, errors
+}
+
+// Creates or updates the objects of a placement on the Kubernetes cluster of the manager.
+// It returns the applied objects, which are not returned if the namespace of the application is not available.
+func (manager *Manager) applyClusterPlacement(application *model.Application, infrastructure *model.Infrastructure, placement *model.Placement) (*clusterObjects, []ObjectResult, []error) {
+	log.Println("Performing placement")
+
+	created := &createdObjects{}
+
+	namespaceCreated, err := manager.ensureNamespace(application)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	created.namespace = namespaceCreated
+
+	namespace := manager.getNamespace(application)
+
+	errors := make([]error, 0)
+	results := make([]ObjectResult, 0)
+
+	// Build all the objects before creating anything
+	objects := &applicationObjects{
+		configMaps:  getConfigMaps(application),
+		secrets:     append(getSecrets(application), getRegistrySecrets(application)...),
+		claims:      getPersistentVolumeClaims(application, placement),
+		deployments: make([]*appsv1.Deployment, 0, len(placement.Assignments)),
+		services:    make([]*apiv1.Service, 0),
+		ingresses:   getIngresses(application, placement, manager.config.IngressClass),
+
+		networkPolicies: manager.getNetworkPolicies(application),
+	}
+
+	for _, assignment := range placement.Assignments {
+		deployment, assignmentServices, err := manager.createDeploymentFromAssignment(application, infrastructure, placement, &assignment)
 		if err != nil {
-			log.Printf("Cannot create a Deployment for application %s and assignment (%s, %s): %s\n", application.ID, assignment.ServiceID, assignment.NodeID, err)
+			log.Printf("Cannot get Deployment and Services for application %s and assignment (%s, %s): %s\n", application.ID, assignment.ServiceID, assignment.NodeID, err)
 			errors = append(errors, err)
 			continue
-		} else {
-			log.Printf("Deployment %s created.\n", assignment.ServiceID)
 		}
 
-		for _, s := range services {
-			serviceResult, err := servicesClient.Create(s)
-			if err != nil {
-				log.Printf("Cannot create a Service for app service %s: %s\n", assignment.ServiceID, err)
-				errors = append(errors, err)
-				continue
-			} else {
-				log.Printf("Service %s created. Ports: %v\n", s.Name, serviceResult.Spec.Ports)
-			}
+		objects.deployments = append(objects.deployments, deployment)
+		objects.services = append(objects.services, assignmentServices...)
+	}
+
+	if len(errors) == 0 {
+		if err := manager.checkConflicts(application, namespace, objects); err != nil {
+			errors = append(errors, err)
 		}
 	}
 
-	if len(errors) > 0 {
-		return errors
+	if len(errors) == 0 {
+		results = manager.applyObjects(application, namespace, objects, created)
+		errors = append(errors, getResultErrors(results)...)
 	}
 
-	return nil
+	applied := &clusterObjects{
+		manager:   manager,
+		namespace: namespace,
+		objects:   objects,
+		created:   created,
+	}
+
+	return applied, results, errors
 }
 
-func getPullPolicy(image model.Image) apiv1.PullPolicy {
-	if image.Local {
-		return apiv1.PullNever
+// Creates or updates the objects of an application owned by the application owner.
+// Created objects are tracked to allow a rollback.
+func (manager *Manager) applyObjects(application *model.Application, namespace string, objects *applicationObjects, created *createdObjects) []ObjectResult {
+	owner, ownerAction, err := manager.createOwner(application, namespace)
+	if err != nil {
+		return []ObjectResult{newObjectResult("ConfigMap", getOwnerName(application), ActionFailed, err)}
+	}
+
+	results := []ObjectResult{newObjectResult("ConfigMap", owner.Name, ownerAction, nil)}
+	if ownerAction == ActionCreated {
+		created.owner = owner
 	}
 
-	return apiv1.PullAlways
-}
+	ownerReferences := []metav1.OwnerReference{getOwnerReference(owner)}
 
-func getSecContext(image model.Image) *apiv1.SecurityContext {
-	tt := true
-	secContext := &apiv1.SecurityContext{}
-	// Set privileged mode
-	if image.Privileged {
-		secContext.Privileged = &tt
+	for _, group := range manager.getObjectGroups(namespace, objects) {
+		for _, o := range group.objects {
+			o.SetOwnerReferences(ownerReferences)
+
+			r := manager.applyObject(application, group.client, o)
+			if r.Action == ActionFailed {
+				log.Printf("Cannot apply %s %s for application %s: %s\n", r.Kind, r.Name, application.ID, r.Error)
+			} else if r.Action == ActionCreated {
+				created.dependents = append(created.dependents, createdObject{group.client, r.Name})
+			}
+
+			results = append(results, r)
+		}
 	}
 
-	return secContext
+	return results
 }
 
 func processEnv(image model.Image) []apiv1.EnvVar {
@@ -363,79 +779,52 @@ func processEnv(image model.Image) []apiv1.EnvVar {
 		iEnv++
 	}
 
+	// Keep a stable order, so that unchanged Deployments are not updated
+	sort.Slice(env, func(i, j int) bool {
+		return env[i].Name < env[j].Name
+	})
+
 	return env
 }
 
-func createServiceFromPort(application *model.Application, assignment *model.Assignment, serviceName string, port model.Port) *apiv1.Service {
-	return &apiv1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: serviceName,
-			Labels: map[string]string{
-				fmt.Sprintf("%s/app", config.FoglutePackageName):     application.Name, // TODO: Use a unique ID
-				fmt.Sprintf("%s/service", config.FoglutePackageName): assignment.ServiceID,
-			},
-		},
-		Spec: apiv1.ServiceSpec{
-			Ports: []apiv1.ServicePort{
-				{
-					Protocol: "TCP",
-					NodePort: int32(port.Expose),
-					Port:     int32(port.HostPort),
-					TargetPort: intstr.IntOrString{
-						Type:   intstr.Int,
-						IntVal: int32(port.ContainerPort),
-						StrVal: string(port.ContainerPort),
-					},
-				},
-			},
-			Selector: map[string]string{
-				fmt.Sprintf("%s/app", config.FoglutePackageName):     application.Name, // TODO: Use a unique ID
-				fmt.Sprintf("%s/service", config.FoglutePackageName): assignment.ServiceID,
-			},
-			Type: apiv1.ServiceTypeLoadBalancer,
-		},
+func getTolerations(service *model.Service) []apiv1.Toleration {
+	tolerations := make([]apiv1.Toleration, len(service.Tolerations))
+	for i, t := range service.Tolerations {
+		tolerations[i] = t.ToKubernetes()
 	}
+
+	return tolerations
 }
 
-func createDeployment(application *model.Application, assignment *model.Assignment, node *model.Node, containers []apiv1.Container) *appsv1.Deployment {
-	deploymentName := fmt.Sprintf("%s-%s", application.ID, assignment.ServiceID)
+func createDeployment(application *model.Application, service *model.Service, assignment *model.Assignment, node *model.Node, containers []apiv1.Container) *appsv1.Deployment {
+	deploymentName := getDeploymentName(application, assignment.ServiceID, assignment.Replica)
+	podLabels := getLabels(application, assignment.ServiceID)
+	podLabels[config.ReplicaLabel] = strconv.Itoa(assignment.Replica)
 
 	return &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: deploymentName,
-			Labels: map[string]string{
-				fmt.Sprintf("%s/app", config.FoglutePackageName):     application.Name, // TODO: Use a unique ID
-				fmt.Sprintf("%s/service", config.FoglutePackageName): assignment.ServiceID,
-			},
-		},
+		ObjectMeta: getObjectMeta(application, assignment.ServiceID, deploymentName, ""),
 		Spec: appsv1.DeploymentSpec{
 			Replicas: pointer.Int32Ptr(1),
-			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{
-				fmt.Sprintf("%s/app", config.FoglutePackageName):     application.Name, // TODO: Use a unique ID
-				fmt.Sprintf("%s/service", config.FoglutePackageName): assignment.ServiceID,
-			}},
+			Selector: &metav1.LabelSelector{MatchLabels: getReplicaSelector(application, assignment.ServiceID, assignment.Replica)},
 			Template: apiv1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						fmt.Sprintf("%s/app", config.FoglutePackageName):     application.Name, // TODO: Use a unique ID
-						fmt.Sprintf("%s/service", config.FoglutePackageName): assignment.ServiceID,
-					},
+					Labels:      podLabels,
+					Annotations: getAnnotations(application, assignment.ServiceID, ""),
 				},
 				Spec: apiv1.PodSpec{
-					NodeName:   node.Name, // Deploy the pod to the selected node only
-					Hostname:   deploymentName,
-					Containers: containers,
+					NodeName:         node.Name, // Deploy the pod to the selected node only
+					Hostname:         deploymentName,
+					Containers:       containers,
+					Tolerations:      getTolerations(service),
+					NodeSelector:     application.NodeSelector,
+					ImagePullSecrets: getImagePullSecrets(application, service),
 				}},
 		},
 	}
 }
 
-func cleanImageName(image model.Image) string {
-	return strings.Replace(image.Name, ":", "", -1)
-}
-
 // Returns Kubernetes Deployments and Services for the given Application according to a given Assignment
-func (manager *Manager) createDeploymentFromAssignment(application *model.Application, infrastructure *model.Infrastructure, assignment *model.Assignment) (*appsv1.Deployment, []*apiv1.Service, error) {
+func (manager *Manager) createDeploymentFromAssignment(application *model.Application, infrastructure *model.Infrastructure, placement *model.Placement, assignment *model.Assignment) (*appsv1.Deployment, []*apiv1.Service, error) {
 	var service *model.Service
 	for _, s := range application.Services {
 		if s.Id == assignment.ServiceID {
@@ -448,6 +837,17 @@ func (manager *Manager) createDeploymentFromAssignment(application *model.Applic
 		return nil, nil, fmt.Errorf("service %s not found in application %s", assignment.ServiceID, application.ID)
 	}
 
+	// Enforce the security requirements of the service
+	mapping, err := getSecurityMapping(service, manager.config.SecurityMappings)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	service, err = enforceSecurity(service, mapping)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	var node *model.Node
 	for _, n := range infrastructure.Nodes {
 		if n.ID == assignment.NodeID {
@@ -462,107 +862,125 @@ func (manager *Manager) createDeploymentFromAssignment(application *model.Applic
 
 	log.Printf("Creating %s deployment...", assignment.ServiceID)
 
+	// All the replicas of a service are behind the same Services
 	services := make([]*apiv1.Service, 0)
+	if isFirstReplica(placement, assignment) {
+		services = append(services, createServices(application, service)...)
+	}
+
+	if hasLocalFlows(application, service) {
+		services = append(services, createReplicaServices(application, service, assignment.Replica)...)
+	}
+
 	containers := make([]apiv1.Container, 0)
+	containerNames := make(map[string]bool)
+	portNames := make(map[string]bool)
+
+	volumes, volumeMounts := getConfigVolumes(application, service)
+	serviceVolumes, serviceVolumeMounts := getServiceVolumes(application, service, assignment.Replica)
+	volumes = append(volumes, serviceVolumes...)
+	deviceVolumes, deviceVolumeMounts := getDeviceVolumes(service)
+	volumes = append(volumes, deviceVolumes...)
+	for i := range volumeMounts {
+		volumeMounts[i] = append(volumeMounts[i], serviceVolumeMounts[i]...)
+		volumeMounts[i] = append(volumeMounts[i], deviceVolumeMounts[i]...)
+	}
 
-	for _, image := range service.Images {
+	for imageIndex, image := range service.Images {
 		// Image pull policy
-		pullPolicy := getPullPolicy(image)
+		pullPolicy := getPullPolicy(application, image)
 
-		secContext := getSecContext(image)
+		secContext := getSecContext(image, manager.config.SecurityPolicy)
 
 		// Checking env variables
 		log.Printf("Environment variables to set: %v\n", image.Env)
 
 		env := processEnv(image)
+		env = append(env, getDiscoveryEnv(application, service, assignment, placement, image)...)
+		configEnv, envFrom := getConfigEnv(application, image)
+		env = append(env, configEnv...)
 
-		var ports []apiv1.ContainerPort
-		if len(image.Ports) > 0 {
-			ports = make([]apiv1.ContainerPort, len(image.Ports))
-
-			for i, port := range image.Ports {
-				ports[i].Name = "http"
-				ports[i].Protocol = apiv1.ProtocolTCP
-				ports[i].ContainerPort = int32(port.ContainerPort)
-				ports[i].HostPort = int32(port.HostPort)
+		ports := getContainerPorts(image, portNames)
 
-				if port.Expose > 0 {
-					serviceName := port.Name
-
-					// Create a service for it
-					s := createServiceFromPort(application, assignment, serviceName, port)
-
-					services = append(services, s)
-				}
-			}
-		}
-
-		cleanImageName := cleanImageName(image)
-		containerName := fmt.Sprintf("%s-%s", service.Id, cleanImageName)
+		containerName := getContainerName(service, image, imageIndex, containerNames)
 
 		// Add a container for each image found
-		containers = append(containers, apiv1.Container{
+		container := apiv1.Container{
 			Name:            containerName,
 			Image:           image.Name,
+			Command:         image.Command,
+			Args:            image.Args,
+			WorkingDir:      image.WorkingDir,
 			ImagePullPolicy: pullPolicy,
 			Ports:           ports,
 			SecurityContext: secContext,
 			Env:             env,
-		})
+			EnvFrom:         envFrom,
+			VolumeMounts:    volumeMounts[imageIndex],
+		}
+
+		if image.LivenessProbe != nil {
+			container.LivenessProbe = image.LivenessProbe.ToKubernetes(&service.Images[imageIndex])
+		}
+
+		if image.ReadinessProbe != nil {
+			container.ReadinessProbe = image.ReadinessProbe.ToKubernetes(&service.Images[imageIndex])
+		}
+
+		containers = append(containers, container)
+	}
+
+	// Every container requests its share of the service resources
+	requests := getContainerRequests(service, toResources(manager.config.HWUnit))
+	for i := range containers {
+		containers[i].Resources = getResourceRequirements(service.Images[i], requests[i])
+	}
+
+	deployment := createDeployment(application, service, assignment, node, containers)
+	deployment.Spec.Template.Spec.Volumes = volumes
+
+	for name, value := range mapping.Annotations {
+		deployment.Spec.Template.Annotations[name] = value
+	}
+
+	if shaping, enabled := manager.getServiceShaping(application, service.Id); enabled {
+		for name, value := range getShapingAnnotations(shaping) {
+			deployment.Spec.Template.Annotations[name] = value
+		}
 	}
 
-	deployment := createDeployment(application, assignment, node, containers)
+	for name, value := range getSeccompAnnotations(service, containers) {
+		deployment.Spec.Template.Annotations[name] = value
+	}
+
+	if mapping.RuntimeClass != "" {
+		deployment.Spec.Template.Spec.RuntimeClassName = &mapping.RuntimeClass
+	}
+
+	// Persistent volumes cannot be attached to the old and the new pod at the same time
+	if service.HasPersistentVolumes() {
+		deployment.Spec.Strategy.Type = appsv1.RecreateDeploymentStrategyType
+	}
 
 	return deployment, services, nil
 }
 
-// Deletes an application from the Kubernetes cluster
+// Deletes an application from the Kubernetes clusters
 func (manager *Manager) delete(application *model.Application) []error {
 	log.Printf("Call to delete with app: %s (%s)\n", application.ID, application.Name)
 
 	startTime := time.Now()
 
-	deploymentsClient := manager.clientset.AppsV1().Deployments(apiv1.NamespaceDefault)
-	serviceClient := manager.clientset.CoreV1().Services(apiv1.NamespaceDefault)
-
 	errors := make([]error, 0)
+	for _, c := range manager.clusters {
+		view := manager.withCluster(c)
 
-	for _, s := range application.Services {
-		deploymentName := fmt.Sprintf("%s-%s", application.ID, s.Id)
-		deletePolicy := metav1.DeletePropagationForeground
-
-		log.Printf("Deleting Deployment %s (%s)...\n", s.Id, deploymentName)
+		_, clusterErrors := view.deleteFromCluster(application)
+		errors = append(errors, clusterErrors...)
 
-		err := deploymentsClient.Delete(deploymentName, &metav1.DeleteOptions{
-			PropagationPolicy: &deletePolicy,
-		})
-		if err != nil {
-			log.Printf("Cannot delete Deployment %s: %s\n", deploymentName, err)
+		if err := view.deleteNamespace(application); err != nil {
 			errors = append(errors, err)
-		} else {
-			log.Printf("Deployment %s deleted.\n", s.Id)
-		}
-
-		for _, image := range s.Images {
-			for _, port := range image.Ports {
-				if port.Expose > 0 {
-					// Remove the associated service
-					serviceName := port.Name
-
-					log.Printf("Deleting Service %s...\n", serviceName)
-
-					if err := serviceClient.Delete(serviceName, &metav1.DeleteOptions{
-						PropagationPolicy: &deletePolicy,
-					}); err != nil {
-						log.Printf("Cannot delete Service %s: %s\n", serviceName, err)
-						errors = append(errors, err)
-					} else {
-						log.Printf("Service %s deleted.\n", serviceName)
-					}
-				}
-			}
 		}
-
 	}
 
 	elapsed := time.Since(startTime)
@@ -575,18 +993,34 @@ func (manager *Manager) delete(application *model.Application) []error {
 	return nil
 }
 
-// Performs the redeploy of an application
-// It first delete the application and then start it again.
-func (manager *Manager) redeploy(application *model.Application) (*model.Placement, []error) {
-	log.Printf("Redeploying application %s...\n", application.Name)
+// Deletes the objects of an application from the Kubernetes cluster of the manager
+func (manager *Manager) deleteFromCluster(application *model.Application) ([]ObjectResult, []error) {
+	namespace := manager.getNamespace(application)
 
-	if err := manager.delete(application); err != nil {
-		log.Printf("Application %s delete error: %s\n", application.Name, err)
-		return nil, err
+	// An empty desired state prunes every object of the application but its owner
+	results := manager.pruneObjects(application, namespace, &applicationObjects{})
+
+	for _, r := range results {
+		log.Printf("%s %s %s\n", r.Kind, r.Name, r.Action)
 	}
 
-	placement, err := manager.deploy(application)
-	if err != nil && placement == nil {
+	errors := getResultErrors(results)
+
+	// Remove the remaining dependents
+	if err := manager.deleteOwner(application, namespace); err != nil {
+		errors = append(errors, err)
+	}
+
+	return results, errors
+}
+
+// Performs the redeploy of an application.
+// The new placement is applied over the objects of the application, and objects not needed anymore are deleted.
+func (manager *Manager) redeploy(application *model.Application) (*Deploy, []error) {
+	log.Printf("Redeploying application %s...\n", application.Name)
+
+	d, err := manager.deploy(application)
+	if err != nil && d == nil {
 		log.Printf("Application %s deploy error: %s\n", application.Name, err)
 		return nil, err
 	}
@@ -597,16 +1031,38 @@ func (manager *Manager) redeploy(application *model.Application) (*model.Placeme
 		log.Printf("Application %s redeployed successfully\n", application.Name)
 	}
 
-	return placement, nil
+	return d, nil
 }
 
-// Returns the infrastructure based on Kubernetes cluster nodes
-func (manager *Manager) getInfrastructure() (*model.Infrastructure, error) {
-	nodes, err := manager.GetNodes()
+// Returns the infrastructure based on Kubernetes cluster nodes that can host the application.
+// Only nodes matching both FogLute and application node selectors are included.
+func (manager *Manager) getInfrastructure(application *model.Application) (*model.Infrastructure, error) {
+	allNodes, err := manager.GetNodes()
 	if err != nil {
 		return nil, err
 	}
 
+	globalSelector, err := labels.Parse(manager.config.NodeSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	appSelector := labels.SelectorFromSet(application.NodeSelector)
+
+	nodes := make([]model.Node, 0, len(allNodes))
+	for _, n := range allNodes {
+		nodeLabels := labels.Set(n.Node.Labels)
+		if globalSelector.Matches(nodeLabels) && appSelector.Matches(nodeLabels) {
+			nodes = append(nodes, n)
+		}
+	}
+
+	return manager.linkNodes(nodes), nil
+}
+
+// Returns the infrastructure made of the complete graph of the given nodes.
+// Links between nodes of different clusters have the configured inter-cluster latency and bandwidth.
+func (manager *Manager) linkNodes(nodes []model.Node) *model.Infrastructure {
 	// Create the complete graph of node
 	linksCount := len(nodes) * (len(nodes) - 1)
 	i := &model.Infrastructure{
@@ -622,6 +1078,10 @@ func (manager *Manager) getInfrastructure() (*model.Infrastructure, error) {
 				i.Links[j].Probability = 1
 				i.Links[j].Bandwidth = defaultLinkBandwidth
 				i.Links[j].Latency = defaultLinkLatency
+				if src.Cluster != dst.Cluster {
+					i.Links[j].Bandwidth = manager.config.InterClusterBandwidth
+					i.Links[j].Latency = manager.config.InterClusterLatency
+				}
 				i.Links[j].Src = src.Name
 				i.Links[j].Dst = dst.Name
 
@@ -630,12 +1090,27 @@ func (manager *Manager) getInfrastructure() (*model.Infrastructure, error) {
 		}
 	}
 
-	return i, nil
+	return i
 }
 
 // Get active Kubernetes cluster nodes
 func (manager *Manager) GetNodes() ([]model.Node, error) {
-	nodes := manager.nodeWatcher.GetNodes()
+	nodes := make([]model.Node, 0)
+	names := make(map[string]string)
+
+	for _, c := range manager.clusters {
+		for _, n := range convertNodes(c.nodeWatcher.GetNodes(), manager.config.ResourceWeights) {
+			// Pods are bound to nodes by name, which must identify a node across clusters
+			if other, exists := names[n.Name]; exists {
+				log.Printf("Warning: node %s of cluster %s has the same name of a node of cluster %s, ignoring it\n", n.Name, c.name, other)
+				continue
+			}
+			names[n.Name] = c.name
+
+			n.Cluster = c.name
+			nodes = append(nodes, n)
+		}
+	}
 
-	return convertNodes(nodes), nil
+	return nodes, nil
 }
@@ -0,0 +1,164 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"fmt"
+	"foglute/internal/model"
+	"foglute/pkg/config"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"log"
+)
+
+// Returns the namespace in which the objects of an application are created
+func (manager *Manager) getNamespace(application *model.Application) string {
+	if application.Namespace != "" {
+		return application.Namespace
+	}
+
+	return manager.config.Namespace
+}
+
+// Creates the namespace of an application if it does not exist.
+// Namespaces created by FogLute are labelled with the application they belong to.
+// It returns true if the namespace has been created.
+func (manager *Manager) ensureNamespace(application *model.Application) (bool, error) {
+	name := manager.getNamespace(application)
+
+	namespacesClient := manager.clientset.CoreV1().Namespaces()
+
+	_, err := namespacesClient.Get(name, metav1.GetOptions{})
+	if err == nil {
+		return false, nil
+	}
+
+	if !errors.IsNotFound(err) {
+		return false, fmt.Errorf("cannot get namespace %s: %s", name, err)
+	}
+
+	// Only application namespaces are created
+	if application.Namespace == "" {
+		return false, fmt.Errorf("namespace %s does not exist", name)
+	}
+
+	log.Printf("Creating namespace %s...\n", name)
+
+	_, err = namespacesClient.Create(&apiv1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				config.AppLabel:       labelValue(application.ID),
+				config.ManagedByLabel: config.ManagedByValue,
+			},
+			Annotations: map[string]string{
+				config.AppIDAnnotation: application.ID,
+			},
+		},
+	})
+	if err != nil {
+		if errors.IsAlreadyExists(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("cannot create namespace %s: %s", name, err)
+	}
+
+	log.Printf("Namespace %s created.\n", name)
+
+	return true, nil
+}
+
+// Name of the ConfigMap that Kubernetes creates in every namespace
+const rootCAConfigMapName = "kube-root-ca.crt"
+
+// Returns true if an object is created by Kubernetes in every namespace
+func isNamespaceDefault(object metav1.Object) bool {
+	switch o := object.(type) {
+	case *apiv1.Secret:
+		return o.Type == apiv1.SecretTypeServiceAccountToken
+	case *apiv1.ConfigMap:
+		return o.Name == rootCAConfigMapName
+	}
+
+	return false
+}
+
+// Returns the first object of a namespace that is not managed by FogLute for the application, if any.
+// The owners and objects of other applications, as well as the objects created by users, are found.
+func (manager *Manager) findForeignObject(application *model.Application, namespace string) (string, error) {
+	for _, group := range manager.getObjectGroups(namespace, &applicationObjects{}) {
+		objects, err := group.client.list(metav1.ListOptions{})
+		if err != nil {
+			return "", fmt.Errorf("cannot list %s objects of namespace %s: %s", group.client.kind, namespace, err)
+		}
+
+		for _, o := range objects {
+			if isOwnedBy(o, application) || isNamespaceDefault(o) {
+				continue
+			}
+
+			if id, exists := o.GetAnnotations()[config.AppIDAnnotation]; exists {
+				return fmt.Sprintf("%s %s of application %s", group.client.kind, o.GetName(), id), nil
+			}
+
+			return fmt.Sprintf("%s %s", group.client.kind, o.GetName()), nil
+		}
+	}
+
+	return "", nil
+}
+
+// Deletes the namespace of an application if it has been created by FogLute for that application
+// and it does not contain objects of other applications or users.
+func (manager *Manager) deleteNamespace(application *model.Application) error {
+	if application.Namespace == "" {
+		return nil
+	}
+
+	namespacesClient := manager.clientset.CoreV1().Namespaces()
+
+	ns, err := namespacesClient.Get(application.Namespace, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+
+		return fmt.Errorf("cannot get namespace %s: %s", application.Namespace, err)
+	}
+
+	if !isOwnedBy(ns, application) {
+		log.Printf("Namespace %s is not owned by application %s, keeping it\n", ns.Name, application.ID)
+		return nil
+	}
+
+	foreign, err := manager.findForeignObject(application, ns.Name)
+	if err != nil {
+		return err
+	}
+
+	if foreign != "" {
+		log.Printf("Namespace %s also contains %s, keeping it\n", ns.Name, foreign)
+		return nil
+	}
+
+	log.Printf("Deleting namespace %s...\n", ns.Name)
+
+	deletePolicy := metav1.DeletePropagationForeground
+	if err := namespacesClient.Delete(ns.Name, &metav1.DeleteOptions{
+		PropagationPolicy: &deletePolicy,
+	}); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("cannot delete namespace %s: %s", ns.Name, err)
+	}
+
+	log.Printf("Namespace %s deleted.\n", ns.Name)
+
+	return nil
+}
@@ -0,0 +1,220 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"fmt"
+	"foglute/internal/model"
+	"foglute/pkg/config"
+	"hash/fnv"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Maximum length of DNS-1123 labels and label values
+	maxNameLength = 63
+)
+
+// Returns a short hash of a string
+func shortHash(s string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(s))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// Returns a valid DNS label built joining the given parts.
+// If the joined parts are not a valid name, invalid characters are replaced, the name is truncated and a hash
+// of the original string is appended to keep names unique.
+// If mustStartWithLetter is true, the name is also a valid DNS-1035 label, as required by Service names.
+func makeName(mustStartWithLetter bool, parts ...string) string {
+	original := strings.Join(parts, "-")
+
+	var b strings.Builder
+	lastDash := false
+	for _, c := range strings.ToLower(original) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+			lastDash = false
+		} else if !lastDash {
+			b.WriteRune('-')
+			lastDash = true
+		}
+	}
+
+	name := strings.Trim(b.String(), "-")
+	if name == "" || (mustStartWithLetter && (name[0] < 'a' || name[0] > 'z')) {
+		name = "f" + name
+	}
+
+	if name == original && len(name) <= maxNameLength {
+		return name
+	}
+
+	suffix := "-" + shortHash(original)
+	if len(name) > maxNameLength-len(suffix) {
+		name = strings.TrimRight(name[:maxNameLength-len(suffix)], "-")
+	}
+
+	return name + suffix
+}
+
+// Returns a valid label value for a string
+func labelValue(value string) string {
+	if len(validation.IsValidLabelValue(value)) == 0 {
+		return value
+	}
+
+	return makeName(false, value)
+}
+
+// Returns the name of the Deployment of a replica of a service.
+// The first replica keeps the name of the Deployment of a service with a single instance.
+func getDeploymentName(application *model.Application, serviceID string, replica int) string {
+	if replica == 0 {
+		return makeName(false, application.ID, serviceID)
+	}
+
+	return makeName(false, application.ID, serviceID, "replica", strconv.Itoa(replica))
+}
+
+// Returns the name of the Kubernetes Service that exposes a group of ports of a service
+func getServiceName(application *model.Application, serviceID string, group string) string {
+	return makeName(true, application.ID, serviceID, group)
+}
+
+// Returns the name of the container that runs an image of a service.
+// The index of the image disambiguates images with the same name.
+func getContainerName(service *model.Service, image model.Image, index int, used map[string]bool) string {
+	// Remove registry and tag
+	imageName := path.Base(strings.SplitN(image.Name, "@", 2)[0])
+	if i := strings.LastIndex(imageName, ":"); i >= 0 {
+		imageName = imageName[:i]
+	}
+
+	name := makeName(false, service.Id, imageName)
+	if used[name] {
+		name = makeName(false, service.Id, imageName, fmt.Sprintf("%d", index))
+	}
+	used[name] = true
+
+	return name
+}
+
+// Returns the labels that select the objects of a service of an application
+func getSelector(application *model.Application, serviceID string) map[string]string {
+	return map[string]string{
+		config.AppLabel:     labelValue(application.ID),
+		config.ServiceLabel: labelValue(serviceID),
+	}
+}
+
+// Returns the labels that select the pods of a replica of a service of an application
+func getReplicaSelector(application *model.Application, serviceID string, replica int) map[string]string {
+	labels := getSelector(application, serviceID)
+	labels[config.ReplicaLabel] = strconv.Itoa(replica)
+
+	return labels
+}
+
+// Returns the labels of the objects of a service of an application
+func getLabels(application *model.Application, serviceID string) map[string]string {
+	labels := getSelector(application, serviceID)
+	labels[config.ManagedByLabel] = config.ManagedByValue
+
+	return labels
+}
+
+// Returns the annotations that store the original names of the objects of a service of an application
+func getAnnotations(application *model.Application, serviceID string, originalName string) map[string]string {
+	annotations := map[string]string{
+		config.AppIDAnnotation:     application.ID,
+		config.AppNameAnnotation:   application.Name,
+		config.ServiceIDAnnotation: serviceID,
+	}
+
+	if originalName != "" {
+		annotations[config.OriginalNameAnnotation] = originalName
+	}
+
+	return annotations
+}
+
+// Returns the metadata of an object of a service of an application
+func getObjectMeta(application *model.Application, serviceID string, name string, originalName string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        name,
+		Labels:      getLabels(application, serviceID),
+		Annotations: getAnnotations(application, serviceID, originalName),
+	}
+}
+
+// Returns the metadata of an object that belongs to the whole application rather than to one of its services
+func getApplicationObjectMeta(application *model.Application, name string, originalName string) metav1.ObjectMeta {
+	meta := getObjectMeta(application, "", name, originalName)
+	delete(meta.Labels, config.ServiceLabel)
+	delete(meta.Annotations, config.ServiceIDAnnotation)
+
+	return meta
+}
+
+// Returns true if the object is managed by FogLute for the application
+func isOwnedBy(object metav1.Object, application *model.Application) bool {
+	labels := object.GetLabels()
+	return labels[config.ManagedByLabel] == config.ManagedByValue &&
+		labels[config.AppLabel] == labelValue(application.ID) &&
+		object.GetAnnotations()[config.AppIDAnnotation] == application.ID
+}
+
+// Checks that no two objects of the same kind get the same name.
+// Parts of names are joined with dashes, so different services, replicas or ports can produce the same name.
+func checkDuplicateNames(application *model.Application, groups []objectGroup) error {
+	for _, group := range groups {
+		names := make(map[string]bool)
+		for _, o := range group.objects {
+			if names[o.GetName()] {
+				return fmt.Errorf("more than one %s of application %s is named %s", strings.ToLower(group.client.kind), application.ID, o.GetName())
+			}
+			names[o.GetName()] = true
+		}
+	}
+
+	return nil
+}
+
+// Checks that none of the objects to create already exists unless it is managed by FogLute for the same application
+func (manager *Manager) checkConflicts(application *model.Application, namespace string, objects *applicationObjects) error {
+	groups := manager.getObjectGroups(namespace, objects)
+	if err := checkDuplicateNames(application, groups); err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		for _, o := range group.objects {
+			existing, err := group.client.get(o.GetName())
+			if err != nil {
+				if errors.IsNotFound(err) {
+					continue
+				}
+
+				return fmt.Errorf("cannot check %s %s: %s", group.client.kind, o.GetName(), err)
+			}
+
+			if !isOwnedBy(existing, application) {
+				return fmt.Errorf("%s %s/%s already exists and is not managed by FogLute for application %s", strings.ToLower(group.client.kind), namespace, o.GetName(), application.ID)
+			}
+		}
+	}
+
+	return nil
+}
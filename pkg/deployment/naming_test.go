@@ -0,0 +1,182 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"foglute/internal/model"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"strings"
+	"testing"
+)
+
+func TestMakeName(t *testing.T) {
+	long := strings.Repeat("a", 70)
+
+	tests := []struct {
+		name                string
+		mustStartWithLetter bool
+		parts               []string
+		want                string
+	}{
+		{"valid name", false, []string{"gioplants", "frontend"}, "gioplants-frontend"},
+		{"digit first", false, []string{"1app", "db"}, "1app-db"},
+		{"digit first as DNS-1035", true, []string{"1app", "db"}, "f1app-db-" + shortHash("1app-db")},
+		{"upper case", false, []string{"GioPlants", "Frontend"}, "gioplants-frontend-" + shortHash("GioPlants-Frontend")},
+		{"invalid characters", false, []string{"gio_plants", "front.end"}, "gio-plants-front-end-" + shortHash("gio_plants-front.end")},
+		{"repeated invalid characters", false, []string{"a__b", "c"}, "a-b-c-" + shortHash("a__b-c")},
+		{"leading and trailing dashes", false, []string{"_app_", "x_"}, "app-x-" + shortHash("_app_-x_")},
+		{"only invalid characters", false, []string{"__"}, "f-" + shortHash("__")},
+		{"exactly 63 characters", false, []string{long[:63]}, long[:63]},
+		{"too long", false, []string{long}, long[:54] + "-" + shortHash(long)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := makeName(tt.mustStartWithLetter, tt.parts...)
+			if got != tt.want {
+				t.Errorf("makeName(%v, %q) = %q, want %q", tt.mustStartWithLetter, tt.parts, got, tt.want)
+			}
+
+			if errs := validation.IsDNS1123Label(got); len(errs) > 0 {
+				t.Errorf("makeName(%v, %q) = %q is not a DNS-1123 label: %s", tt.mustStartWithLetter, tt.parts, got, errs)
+			}
+
+			if tt.mustStartWithLetter {
+				if errs := validation.IsDNS1035Label(got); len(errs) > 0 {
+					t.Errorf("makeName(%v, %q) = %q is not a DNS-1035 label: %s", tt.mustStartWithLetter, tt.parts, got, errs)
+				}
+			}
+		})
+	}
+}
+
+func TestMakeNameCollisions(t *testing.T) {
+	long := strings.Repeat("service", 10)
+
+	tests := []struct {
+		name string
+		a    []string
+		b    []string
+	}{
+		{"same normalized name", []string{"app", "my_service"}, []string{"app", "my.service"}},
+		{"case only", []string{"app", "Service"}, []string{"app", "service"}},
+		{"valid and normalized", []string{"app", "my-service"}, []string{"app", "my_service"}},
+		{"same truncated prefix", []string{"app", long + "-a"}, []string{"app", long + "-b"}},
+		{"split at an invalid character", []string{"a-b", "c"}, []string{"a", "b_c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := makeName(false, tt.a...)
+			b := makeName(false, tt.b...)
+			if a == b {
+				t.Errorf("makeName(%q) and makeName(%q) both return %q", tt.a, tt.b, a)
+			}
+		})
+	}
+}
+
+func TestCheckDuplicateNames(t *testing.T) {
+	application := &model.Application{ID: "app"}
+
+	deployment := func(name string) metav1.Object {
+		return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	service := func(name string) metav1.Object {
+		return &apiv1.Service{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+
+	tests := []struct {
+		name        string
+		deployments []metav1.Object
+		services    []metav1.Object
+		fails       bool
+	}{
+		{"distinct names",
+			[]metav1.Object{deployment(getDeploymentName(application, "web", 0)), deployment(getDeploymentName(application, "db", 0))},
+			[]metav1.Object{service(getServiceName(application, "web", "http")), service(getServiceName(application, "db", "tcp"))}, false},
+		{"same name of different kinds",
+			[]metav1.Object{deployment(getDeploymentName(application, "web", 0))},
+			[]metav1.Object{service(makeName(true, application.ID, "web"))}, false},
+		{"ports split at a dash", nil,
+			[]metav1.Object{service(getServiceName(application, "web", "api-http")), service(getServiceName(application, "web-api", "http"))}, true},
+		{"replica and service with a dash",
+			[]metav1.Object{deployment(getDeploymentName(application, "db", 1)), deployment(getDeploymentName(application, "db-replica-1", 0))}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups := []objectGroup{
+				{&objectClient{kind: "Deployment"}, tt.deployments},
+				{&objectClient{kind: "Service"}, tt.services},
+			}
+
+			err := checkDuplicateNames(application, groups)
+			if (err != nil) != tt.fails {
+				t.Errorf("error = %v, want error %v", err, tt.fails)
+			}
+		})
+	}
+}
+
+func TestLabelValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"valid value", "gioplants", "gioplants"},
+		{"valid mixed case", "GioPlants_1.0", "GioPlants_1.0"},
+		{"invalid characters", "gio plants", "gio-plants-" + shortHash("gio plants")},
+		{"too long", strings.Repeat("x", 64), strings.Repeat("x", 54) + "-" + shortHash(strings.Repeat("x", 64))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := labelValue(tt.value)
+			if got != tt.want {
+				t.Errorf("labelValue(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+
+			if errs := validation.IsValidLabelValue(got); len(errs) > 0 {
+				t.Errorf("labelValue(%q) = %q is not a valid label value: %s", tt.value, got, errs)
+			}
+		})
+	}
+}
+
+func TestGetContainerName(t *testing.T) {
+	service := &model.Service{Id: "device-ms"}
+
+	tests := []struct {
+		name   string
+		images []string
+		want   []string
+	}{
+		{"registry and tag", []string{"registry.example.com:5000/gio/device-ms:1.0"}, []string{"device-ms-device-ms"}},
+		{"digest", []string{"mongo@sha256:0123"}, []string{"device-ms-mongo"}},
+		{"different images", []string{"gio-device-ms:latest", "mongo:latest"}, []string{"device-ms-gio-device-ms", "device-ms-mongo"}},
+		{"same image", []string{"mongo:4", "mongo:5"}, []string{"device-ms-mongo", "device-ms-mongo-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			used := make(map[string]bool)
+			for i, image := range tt.images {
+				got := getContainerName(service, model.Image{Name: image}, i, used)
+				if got != tt.want[i] {
+					t.Errorf("getContainerName(%q, %d) = %q, want %q", image, i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
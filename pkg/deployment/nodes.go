@@ -11,23 +11,25 @@ package deployment
 import (
 	"foglute/internal/model"
 	"foglute/pkg/config"
+	"foglute/pkg/infrastructure"
 	apiv1 "k8s.io/api/core/v1"
+	"math"
 	"strconv"
 	"strings"
 )
 
 // Converts a list of Kubernetes nodes to a list of Manager nodes
-func convertNodes(nodes []apiv1.Node) []model.Node {
+func convertNodes(nodes []apiv1.Node, weights config.ResourceWeights) []model.Node {
 	ret := make([]model.Node, len(nodes))
 	for i, n := range nodes {
-		ret[i] = convertNode(n)
+		ret[i] = convertNode(n, weights)
 	}
 
 	return ret
 }
 
 // Converts a Kubernetes node to a Manager node
-func convertNode(node apiv1.Node) model.Node {
+func convertNode(node apiv1.Node, weights config.ResourceWeights) model.Node {
 	n := model.Node{
 		ID:      string(node.GetUID()),
 		Name:    node.Name,
@@ -37,6 +39,7 @@ func convertNode(node apiv1.Node) model.Node {
 			Latitude:  model.NodeDefaultLatitude,
 		},
 		Profiles: make([]model.NodeProfile, 1),
+		Taints:   infrastructure.GetBlockingTaints(&node),
 		Node:     &node,
 	}
 
@@ -48,6 +51,20 @@ func convertNode(node apiv1.Node) model.Node {
 		n.Location.Latitude = int(lat)
 	}
 
+	// Fallback to the well-known Kubernetes zone label
+	if zone, exists := node.Labels[config.ZoneLabel]; exists {
+		n.Location.Zone = zone
+	} else {
+		n.Location.Zone = node.Labels[apiv1.LabelZoneFailureDomain]
+	}
+
+	// Storage classes are separated by underscores, which are not allowed in their names
+	if classes, exists := node.Labels[config.StorageClassesLabel]; exists {
+		n.StorageClasses = strings.FieldsFunc(classes, func(c rune) bool {
+			return c == '_' || c == ','
+		})
+	}
+
 	n.Profiles[0].Probability = 1
 	if iotCaps, exists := node.Labels[config.IotLabel]; exists {
 		n.Profiles[0].IoTCaps = strings.Split(iotCaps, ",")
@@ -60,17 +77,23 @@ func convertNode(node apiv1.Node) model.Node {
 		n.Profiles[0].SecCaps = make([]string, 0)
 	}
 
-	n.Profiles[0].HWCaps = getHwCaps(&node)
+	n.Profiles[0].Resources = getNodeResources(&node)
+	n.Profiles[0].HWCaps = getHwCaps(&node, n.Profiles[0].Resources, weights)
 
 	return n
 }
 
-// Extracts Hardware capabilities from a node
-func getHwCaps(node *apiv1.Node) int64 {
-	m := node.Status.Capacity.Memory().Value()
-	if m <= 0 {
+// Extracts Hardware capabilities from a node.
+// The hw_caps label takes precedence over the weighted node resources.
+func getHwCaps(node *apiv1.Node, resources model.Resources, weights config.ResourceWeights) int64 {
+	if hwCaps, err := strconv.ParseInt(node.Labels[config.HwCapsLabel], 10, 64); err == nil {
+		return hwCaps
+	}
+
+	units := int64(math.Floor(hwUnits(weights, resources)))
+	if units <= 0 {
 		return model.NodeDefaultHwCaps
 	}
 
-	return m
+	return units
 }
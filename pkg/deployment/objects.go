@@ -0,0 +1,405 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The Kubernetes objects of an application
+type applicationObjects struct {
+	configMaps  []*apiv1.ConfigMap
+	secrets     []*apiv1.Secret
+	claims      []*apiv1.PersistentVolumeClaim
+	deployments []*appsv1.Deployment
+	services    []*apiv1.Service
+	ingresses   []*networkingv1beta1.Ingress
+
+	networkPolicies []*networkingv1.NetworkPolicy
+}
+
+// An objectClient performs the operations of the apply layer on a kind of objects of a namespace
+type objectClient struct {
+	kind string
+
+	get    func(name string) (metav1.Object, error)
+	create func(object metav1.Object) error
+	update func(object metav1.Object) error
+	delete func(name string, options *metav1.DeleteOptions) error
+	list   func(options metav1.ListOptions) ([]metav1.Object, error)
+
+	// Returns the desired state of an object, used to compute its spec hash
+	spec func(object metav1.Object) interface{}
+
+	// Copies to the desired object the fields that are set by Kubernetes and cannot be updated
+	preserve func(desired metav1.Object, existing metav1.Object)
+
+	// Returns true if fields that cannot be updated differ, so that the object must be deleted and created again
+	recreate func(desired metav1.Object, existing metav1.Object) bool
+}
+
+// A group of objects of the same kind
+type objectGroup struct {
+	client  *objectClient
+	objects []metav1.Object
+}
+
+// Returns the objects of an application grouped by kind, in the order in which they must be applied.
+// Objects referenced by others come first.
+func (manager *Manager) getObjectGroups(namespace string, objects *applicationObjects) []objectGroup {
+	configMaps := make([]metav1.Object, len(objects.configMaps))
+	for i, o := range objects.configMaps {
+		configMaps[i] = o
+	}
+
+	secrets := make([]metav1.Object, len(objects.secrets))
+	for i, o := range objects.secrets {
+		secrets[i] = o
+	}
+
+	claims := make([]metav1.Object, len(objects.claims))
+	for i, o := range objects.claims {
+		claims[i] = o
+	}
+
+	deployments := make([]metav1.Object, len(objects.deployments))
+	for i, o := range objects.deployments {
+		deployments[i] = o
+	}
+
+	services := make([]metav1.Object, len(objects.services))
+	for i, o := range objects.services {
+		services[i] = o
+	}
+
+	ingresses := make([]metav1.Object, len(objects.ingresses))
+	for i, o := range objects.ingresses {
+		ingresses[i] = o
+	}
+
+	networkPolicies := make([]metav1.Object, len(objects.networkPolicies))
+	for i, o := range objects.networkPolicies {
+		networkPolicies[i] = o
+	}
+
+	return []objectGroup{
+		{manager.configMapClient(namespace), configMaps},
+		{manager.secretClient(namespace), secrets},
+		{manager.claimClient(namespace), claims},
+		{manager.deploymentClient(namespace), deployments},
+		{manager.serviceClient(namespace), services},
+		{manager.ingressClient(namespace), ingresses},
+		{manager.networkPolicyClient(namespace), networkPolicies},
+	}
+}
+
+func (manager *Manager) deploymentClient(namespace string) *objectClient {
+	client := manager.clientset.AppsV1().Deployments(namespace)
+
+	return &objectClient{
+		kind: "Deployment",
+		get: func(name string) (metav1.Object, error) {
+			return client.Get(name, metav1.GetOptions{})
+		},
+		create: func(object metav1.Object) error {
+			_, err := client.Create(object.(*appsv1.Deployment))
+			return err
+		},
+		update: func(object metav1.Object) error {
+			_, err := client.Update(object.(*appsv1.Deployment))
+			return err
+		},
+		delete: client.Delete,
+		list: func(options metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := client.List(options)
+			if err != nil {
+				return nil, err
+			}
+
+			objects := make([]metav1.Object, len(list.Items))
+			for i := range list.Items {
+				objects[i] = &list.Items[i]
+			}
+
+			return objects, nil
+		},
+		spec: func(object metav1.Object) interface{} {
+			return object.(*appsv1.Deployment).Spec
+		},
+		recreate: deploymentSelectorChanged,
+	}
+}
+
+// Returns true if the selector of a Deployment, which is immutable, changed
+func deploymentSelectorChanged(desired metav1.Object, existing metav1.Object) bool {
+	return !equality.Semantic.DeepEqual(desired.(*appsv1.Deployment).Spec.Selector, existing.(*appsv1.Deployment).Spec.Selector)
+}
+
+func (manager *Manager) serviceClient(namespace string) *objectClient {
+	client := manager.clientset.CoreV1().Services(namespace)
+
+	return &objectClient{
+		kind: "Service",
+		get: func(name string) (metav1.Object, error) {
+			return client.Get(name, metav1.GetOptions{})
+		},
+		create: func(object metav1.Object) error {
+			_, err := client.Create(object.(*apiv1.Service))
+			return err
+		},
+		update: func(object metav1.Object) error {
+			_, err := client.Update(object.(*apiv1.Service))
+			return err
+		},
+		delete: client.Delete,
+		list: func(options metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := client.List(options)
+			if err != nil {
+				return nil, err
+			}
+
+			objects := make([]metav1.Object, len(list.Items))
+			for i := range list.Items {
+				objects[i] = &list.Items[i]
+			}
+
+			return objects, nil
+		},
+		spec: func(object metav1.Object) interface{} {
+			return object.(*apiv1.Service).Spec
+		},
+		preserve: func(desired metav1.Object, existing metav1.Object) {
+			preserveServiceAllocations(desired.(*apiv1.Service), existing.(*apiv1.Service))
+		},
+	}
+}
+
+// Copies to the desired Service the addresses and ports allocated by Kubernetes to the existing one.
+// The cluster IP cannot change, and node ports that are not requested explicitly are kept so that the ports exposed
+// on the nodes do not change on updates.
+func preserveServiceAllocations(desired *apiv1.Service, existing *apiv1.Service) {
+	desired.Spec.ClusterIP = existing.Spec.ClusterIP
+
+	if desired.Spec.Type != apiv1.ServiceTypeNodePort && desired.Spec.Type != apiv1.ServiceTypeLoadBalancer {
+		return
+	}
+
+	for i := range desired.Spec.Ports {
+		d := &desired.Spec.Ports[i]
+		if d.NodePort != 0 {
+			continue
+		}
+
+		for _, e := range existing.Spec.Ports {
+			// Ports are matched by name, or by number if they are unnamed
+			if (d.Name != "" && d.Name == e.Name) || (d.Name == "" && d.Port == e.Port && d.Protocol == e.Protocol) {
+				d.NodePort = e.NodePort
+				break
+			}
+		}
+	}
+
+	if desired.Spec.ExternalTrafficPolicy == apiv1.ServiceExternalTrafficPolicyTypeLocal && desired.Spec.HealthCheckNodePort == 0 {
+		desired.Spec.HealthCheckNodePort = existing.Spec.HealthCheckNodePort
+	}
+}
+
+func (manager *Manager) configMapClient(namespace string) *objectClient {
+	client := manager.clientset.CoreV1().ConfigMaps(namespace)
+
+	return &objectClient{
+		kind: "ConfigMap",
+		get: func(name string) (metav1.Object, error) {
+			return client.Get(name, metav1.GetOptions{})
+		},
+		create: func(object metav1.Object) error {
+			_, err := client.Create(object.(*apiv1.ConfigMap))
+			return err
+		},
+		update: func(object metav1.Object) error {
+			_, err := client.Update(object.(*apiv1.ConfigMap))
+			return err
+		},
+		delete: client.Delete,
+		list: func(options metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := client.List(options)
+			if err != nil {
+				return nil, err
+			}
+
+			objects := make([]metav1.Object, len(list.Items))
+			for i := range list.Items {
+				objects[i] = &list.Items[i]
+			}
+
+			return objects, nil
+		},
+		spec: func(object metav1.Object) interface{} {
+			cm := object.(*apiv1.ConfigMap)
+			return []interface{}{cm.Data, cm.BinaryData}
+		},
+	}
+}
+
+func (manager *Manager) secretClient(namespace string) *objectClient {
+	client := manager.clientset.CoreV1().Secrets(namespace)
+
+	return &objectClient{
+		kind: "Secret",
+		get: func(name string) (metav1.Object, error) {
+			return client.Get(name, metav1.GetOptions{})
+		},
+		create: func(object metav1.Object) error {
+			_, err := client.Create(object.(*apiv1.Secret))
+			return err
+		},
+		update: func(object metav1.Object) error {
+			_, err := client.Update(object.(*apiv1.Secret))
+			return err
+		},
+		delete: client.Delete,
+		list: func(options metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := client.List(options)
+			if err != nil {
+				return nil, err
+			}
+
+			objects := make([]metav1.Object, len(list.Items))
+			for i := range list.Items {
+				objects[i] = &list.Items[i]
+			}
+
+			return objects, nil
+		},
+		spec: func(object metav1.Object) interface{} {
+			s := object.(*apiv1.Secret)
+			return []interface{}{s.Type, s.Data, s.StringData}
+		},
+	}
+}
+
+func (manager *Manager) claimClient(namespace string) *objectClient {
+	client := manager.clientset.CoreV1().PersistentVolumeClaims(namespace)
+
+	return &objectClient{
+		kind: "PersistentVolumeClaim",
+		get: func(name string) (metav1.Object, error) {
+			return client.Get(name, metav1.GetOptions{})
+		},
+		create: func(object metav1.Object) error {
+			_, err := client.Create(object.(*apiv1.PersistentVolumeClaim))
+			return err
+		},
+		update: func(object metav1.Object) error {
+			_, err := client.Update(object.(*apiv1.PersistentVolumeClaim))
+			return err
+		},
+		delete: client.Delete,
+		list: func(options metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := client.List(options)
+			if err != nil {
+				return nil, err
+			}
+
+			objects := make([]metav1.Object, len(list.Items))
+			for i := range list.Items {
+				objects[i] = &list.Items[i]
+			}
+
+			return objects, nil
+		},
+		spec: func(object metav1.Object) interface{} {
+			return object.(*apiv1.PersistentVolumeClaim).Spec
+		},
+		preserve: func(desired metav1.Object, existing metav1.Object) {
+			// Only the requested storage of a bound claim can change
+			d := desired.(*apiv1.PersistentVolumeClaim)
+			requests := d.Spec.Resources.Requests
+			d.Spec = existing.(*apiv1.PersistentVolumeClaim).Spec
+			d.Spec.Resources.Requests = requests
+		},
+	}
+}
+
+func (manager *Manager) ingressClient(namespace string) *objectClient {
+	client := manager.clientset.NetworkingV1beta1().Ingresses(namespace)
+
+	return &objectClient{
+		kind: "Ingress",
+		get: func(name string) (metav1.Object, error) {
+			return client.Get(name, metav1.GetOptions{})
+		},
+		create: func(object metav1.Object) error {
+			_, err := client.Create(object.(*networkingv1beta1.Ingress))
+			return err
+		},
+		update: func(object metav1.Object) error {
+			_, err := client.Update(object.(*networkingv1beta1.Ingress))
+			return err
+		},
+		delete: client.Delete,
+		list: func(options metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := client.List(options)
+			if err != nil {
+				return nil, err
+			}
+
+			objects := make([]metav1.Object, len(list.Items))
+			for i := range list.Items {
+				objects[i] = &list.Items[i]
+			}
+
+			return objects, nil
+		},
+		spec: func(object metav1.Object) interface{} {
+			return object.(*networkingv1beta1.Ingress).Spec
+		},
+	}
+}
+
+func (manager *Manager) networkPolicyClient(namespace string) *objectClient {
+	client := manager.clientset.NetworkingV1().NetworkPolicies(namespace)
+
+	return &objectClient{
+		kind: "NetworkPolicy",
+		get: func(name string) (metav1.Object, error) {
+			return client.Get(name, metav1.GetOptions{})
+		},
+		create: func(object metav1.Object) error {
+			_, err := client.Create(object.(*networkingv1.NetworkPolicy))
+			return err
+		},
+		update: func(object metav1.Object) error {
+			_, err := client.Update(object.(*networkingv1.NetworkPolicy))
+			return err
+		},
+		delete: client.Delete,
+		list: func(options metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := client.List(options)
+			if err != nil {
+				return nil, err
+			}
+
+			objects := make([]metav1.Object, len(list.Items))
+			for i := range list.Items {
+				objects[i] = &list.Items[i]
+			}
+
+			return objects, nil
+		},
+		spec: func(object metav1.Object) interface{} {
+			return object.(*networkingv1.NetworkPolicy).Spec
+		},
+	}
+}
@@ -0,0 +1,179 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"encoding/json"
+	"fmt"
+	"foglute/internal/model"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"log"
+	"time"
+)
+
+const (
+	// Key of the owner ConfigMap that stores the application descriptor
+	ownerApplicationKey = "application.json"
+
+	// Polling settings used while waiting for a previous owner to be deleted
+	ownerDeletionPollInterval = time.Second
+	ownerDeletionTimeout      = 2 * time.Minute
+)
+
+// Returns the name of the ConfigMap that owns all the objects of an application
+func getOwnerName(application *model.Application) string {
+	return makeName(false, application.ID, "foglute")
+}
+
+// Returns an owner reference to the owner of an application.
+// Kubernetes garbage collector removes the dependents of the owner when it is deleted.
+func getOwnerReference(owner *apiv1.ConfigMap) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Name:       owner.Name,
+		UID:        owner.UID,
+	}
+}
+
+// Creates the ConfigMap that owns all the objects of an application.
+// The ConfigMap stores the application descriptor. An existing owner of the same application is updated.
+func (manager *Manager) createOwner(application *model.Application, namespace string) (*apiv1.ConfigMap, ApplyAction, error) {
+	configMapsClient := manager.clientset.CoreV1().ConfigMaps(namespace)
+
+	// Secret values are stored only in the Secrets of the application
+	descriptor, err := json.Marshal(application.Redacted())
+	if err != nil {
+		return nil, ActionFailed, err
+	}
+
+	name := getOwnerName(application)
+
+	owner := &apiv1.ConfigMap{
+		ObjectMeta: getApplicationObjectMeta(application, name, ""),
+		Data: map[string]string{
+			ownerApplicationKey: string(descriptor),
+		},
+	}
+
+	// Wait for the owner of a previous deploy to be deleted
+	var existing *apiv1.ConfigMap
+	err = wait.PollImmediate(ownerDeletionPollInterval, ownerDeletionTimeout, func() (bool, error) {
+		existing, err = configMapsClient.Get(name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			existing = nil
+			return true, nil
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		return existing.DeletionTimestamp == nil, nil
+	})
+	if err != nil {
+		return nil, ActionFailed, fmt.Errorf("cannot get owner %s: %s", name, err)
+	}
+
+	if existing == nil {
+		created, err := configMapsClient.Create(owner)
+		if err != nil {
+			return nil, ActionFailed, fmt.Errorf("cannot create owner %s: %s", name, err)
+		}
+
+		log.Printf("Owner %s created.\n", name)
+
+		return created, ActionCreated, nil
+	}
+
+	if !isOwnedBy(existing, application) {
+		return nil, ActionFailed, fmt.Errorf("configmap %s/%s already exists and is not managed by FogLute for application %s", namespace, name, application.ID)
+	}
+
+	if existing.Data[ownerApplicationKey] == owner.Data[ownerApplicationKey] {
+		return existing, ActionUnchanged, nil
+	}
+
+	existing.Data = owner.Data
+
+	updated, err := configMapsClient.Update(existing)
+	if err != nil {
+		return nil, ActionFailed, fmt.Errorf("cannot update owner %s: %s", name, err)
+	}
+
+	return updated, ActionUpdated, nil
+}
+
+// Deletes the owner of an application.
+// Its dependents are removed by Kubernetes garbage collector.
+func (manager *Manager) deleteOwner(application *model.Application, namespace string) error {
+	name := getOwnerName(application)
+
+	log.Printf("Deleting owner %s...\n", name)
+
+	deletePolicy := metav1.DeletePropagationForeground
+	err := manager.clientset.CoreV1().ConfigMaps(namespace).Delete(name, &metav1.DeleteOptions{
+		PropagationPolicy: &deletePolicy,
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("cannot delete owner %s: %s", name, err)
+	}
+
+	log.Printf("Owner %s deleted.\n", name)
+
+	return nil
+}
+
+// An object created during a deploy attempt
+type createdObject struct {
+	client *objectClient
+	name   string
+}
+
+// The objects created during a deploy attempt
+type createdObjects struct {
+	namespace  bool
+	owner      *apiv1.ConfigMap
+	dependents []createdObject
+}
+
+// Deletes all the objects created during a failed deploy attempt.
+// Objects updated by the attempt are left in their new state.
+func (manager *Manager) rollback(application *model.Application, namespace string, created *createdObjects) []error {
+	log.Printf("Rolling back deploy of application %s...\n", application.ID)
+
+	results := make([]ObjectResult, 0)
+
+	// Dependent objects are deleted before the objects they reference
+	for i := len(created.dependents) - 1; i >= 0; i-- {
+		o := created.dependents[i]
+		results = append(results, manager.deleteObject(o.client, o.name))
+	}
+
+	errs := getResultErrors(results)
+
+	if created.owner != nil {
+		if err := manager.deleteOwner(application, namespace); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if created.namespace {
+		if err := manager.deleteNamespace(application); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	log.Printf("Rollback of application %s completed with %d errors\n", application.ID, len(errs))
+
+	return errs
+}
@@ -0,0 +1,124 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"fmt"
+	"foglute/internal/model"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"strings"
+)
+
+const (
+	// Maximum length of port names
+	maxPortNameLength = 15
+)
+
+// Kubernetes Service types of the port service types
+var serviceTypes = map[string]apiv1.ServiceType{
+	model.ClusterIPService:    apiv1.ServiceTypeClusterIP,
+	model.NodePortService:     apiv1.ServiceTypeNodePort,
+	model.LoadBalancerService: apiv1.ServiceTypeLoadBalancer,
+	model.HeadlessService:     apiv1.ServiceTypeClusterIP,
+}
+
+// Returns a valid port name, unique among the used ones.
+// Port names are lowercase alphanumeric strings with dashes of at most 15 characters and contain a letter.
+func getPortName(name string, port model.Port, used map[string]bool) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(name) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+		} else if b.Len() > 0 && !strings.HasSuffix(b.String(), "-") {
+			b.WriteRune('-')
+		}
+	}
+
+	candidate := strings.Trim(b.String(), "-")
+	if len(candidate) > maxPortNameLength {
+		candidate = strings.TrimRight(candidate[:maxPortNameLength], "-")
+	}
+
+	if candidate == "" || strings.IndexFunc(candidate, func(c rune) bool { return c >= 'a' && c <= 'z' }) < 0 || used[candidate] {
+		candidate = fmt.Sprintf("%s-%d", strings.ToLower(port.GetProtocol()), port.ContainerPort)
+	}
+
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s-%d-%d", strings.ToLower(port.GetProtocol()), port.ContainerPort, i)
+	}
+	used[candidate] = true
+
+	return candidate
+}
+
+// Returns the container ports of an image. used holds the port names already used in the pod.
+func getContainerPorts(image model.Image, used map[string]bool) []apiv1.ContainerPort {
+	if len(image.Ports) == 0 {
+		return nil
+	}
+
+	ports := make([]apiv1.ContainerPort, len(image.Ports))
+	for i, port := range image.Ports {
+		ports[i].Name = getPortName(port.Name, port, used)
+		ports[i].Protocol = apiv1.Protocol(port.GetProtocol())
+		ports[i].ContainerPort = int32(port.ContainerPort)
+		ports[i].HostPort = int32(port.HostPort)
+	}
+
+	return ports
+}
+
+// Returns the Kubernetes Services that expose the ports of a service.
+// Ports with the same service name are exposed by the same Kubernetes Service.
+func createServices(application *model.Application, service *model.Service) []*apiv1.Service {
+	services := make([]*apiv1.Service, 0)
+	byName := make(map[string]*apiv1.Service)
+	portNames := make(map[string]map[string]bool)
+
+	for _, image := range service.Images {
+		for _, port := range image.Ports {
+			if !port.IsExposed() {
+				continue
+			}
+
+			name := port.GetServiceName()
+
+			s, exists := byName[name]
+			if !exists {
+				s = &apiv1.Service{
+					ObjectMeta: getObjectMeta(application, service.Id, getServiceName(application, service.Id, name), name),
+					Spec: apiv1.ServiceSpec{
+						Selector: getSelector(application, service.Id),
+						Type:     serviceTypes[port.GetServiceType()],
+					},
+				}
+
+				if port.GetServiceType() == model.HeadlessService {
+					s.Spec.ClusterIP = apiv1.ClusterIPNone
+				}
+
+				byName[name] = s
+				portNames[name] = make(map[string]bool)
+				services = append(services, s)
+			}
+
+			// Node ports are allocated by Kubernetes unless given
+			s.Spec.Ports = append(s.Spec.Ports, apiv1.ServicePort{
+				Name:       getPortName(port.Name, port, portNames[name]),
+				Protocol:   apiv1.Protocol(port.GetProtocol()),
+				Port:       int32(port.GetServicePort()),
+				NodePort:   int32(port.Expose),
+				TargetPort: intstr.FromInt(port.ContainerPort),
+			})
+		}
+	}
+
+	return services
+}
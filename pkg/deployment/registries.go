@@ -0,0 +1,102 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"foglute/internal/model"
+	apiv1 "k8s.io/api/core/v1"
+	"sort"
+)
+
+var pullPolicies = map[string]apiv1.PullPolicy{
+	model.PullAlways:       apiv1.PullAlways,
+	model.PullIfNotPresent: apiv1.PullIfNotPresent,
+	model.PullNever:        apiv1.PullNever,
+}
+
+// Returns the Kubernetes pull policy of an image
+func getPullPolicy(application *model.Application, image model.Image) apiv1.PullPolicy {
+	return pullPolicies[application.GetPullPolicy(image)]
+}
+
+// Returns the name of the Secret that stores the credentials of a registry
+func getRegistrySecretName(application *model.Application, registry *model.RegistryCredential) string {
+	if registry.Existing != "" {
+		return registry.Existing
+	}
+
+	return makeName(false, application.ID, "registry", registry.Name)
+}
+
+// Returns the name of the Secret referenced by a pull secret of an application
+func getPullSecretName(application *model.Application, pullSecret string) string {
+	if registry, exists := application.GetRegistry(pullSecret); exists {
+		return getRegistrySecretName(application, registry)
+	}
+
+	return pullSecret
+}
+
+// Returns the Secrets to create for the registry credentials of an application.
+// Existing Secrets are not managed by FogLute.
+func getRegistrySecrets(application *model.Application) []*apiv1.Secret {
+	secrets := make([]*apiv1.Secret, 0, len(application.Registries))
+
+	for i := range application.Registries {
+		r := &application.Registries[i]
+		if r.Existing != "" {
+			continue
+		}
+
+		auth := base64.StdEncoding.EncodeToString([]byte(r.Username + ":" + r.Password))
+		dockerConfig, _ := json.Marshal(map[string]interface{}{
+			"auths": map[string]interface{}{
+				r.Server: map[string]string{
+					"username": r.Username,
+					"password": r.Password,
+					"auth":     auth,
+				},
+			},
+		})
+
+		secrets = append(secrets, &apiv1.Secret{
+			ObjectMeta: getApplicationObjectMeta(application, getRegistrySecretName(application, r), r.Name),
+			Type:       apiv1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{
+				apiv1.DockerConfigJsonKey: dockerConfig,
+			},
+		})
+	}
+
+	return secrets
+}
+
+// Returns the Secrets used to pull the images of a service
+func getImagePullSecrets(application *model.Application, service *model.Service) []apiv1.LocalObjectReference {
+	names := make(map[string]bool)
+	for _, image := range service.Images {
+		if pullSecret := application.GetPullSecret(image); pullSecret != "" {
+			names[getPullSecretName(application, pullSecret)] = true
+		}
+	}
+
+	references := make([]apiv1.LocalObjectReference, 0, len(names))
+	for name := range names {
+		references = append(references, apiv1.LocalObjectReference{Name: name})
+	}
+
+	// Keep a stable order, so that unchanged Deployments are not updated
+	sort.Slice(references, func(i, j int) bool {
+		return references[i].Name < references[j].Name
+	})
+
+	return references
+}
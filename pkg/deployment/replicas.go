@@ -0,0 +1,178 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"fmt"
+	"foglute/internal/model"
+)
+
+// A replica of a service of an application
+type replicaRef struct {
+	serviceID string
+	replica   int
+}
+
+// Returns the id of a replica of a service in the analysis
+func getReplicaID(serviceID string, replica int) string {
+	if replica == 0 {
+		return serviceID
+	}
+
+	return fmt.Sprintf("%s_replica%d", serviceID, replica)
+}
+
+// Returns a copy of the application in which each replica of a service is a distinct service, so that the analyzer
+// places each replica. Flows are replicated between all the replicas of their services, splitting the bandwidth
+// between the destination replicas, and latency constraints apply to each combination of replicas.
+// Same zone constraints refer to the first replica of a service, co-location rules pair replicas with the same
+// index and anti-co-location rules apply to all the replicas.
+// It also returns the replica that each service of the copy stands for.
+func expandReplicas(application *model.Application) (*model.Application, map[string]replicaRef, error) {
+	expanded := *application
+	expanded.Services = make([]model.Service, 0, len(application.Services))
+	refs := make(map[string]replicaRef)
+	ids := make(map[string][]string)
+
+	for _, s := range application.Services {
+		n := s.GetReplicas()
+
+		for r := 0; r < n; r++ {
+			replica := s
+			replica.Id = getReplicaID(s.Id, r)
+			if n > 1 {
+				replica.ReplicaOf = s.Id
+			}
+
+			if _, exists := refs[replica.Id]; exists {
+				return nil, nil, fmt.Errorf("replica %d of service %s has the same id of another service", r, s.Id)
+			}
+
+			refs[replica.Id] = replicaRef{serviceID: s.Id, replica: r}
+			ids[s.Id] = append(ids[s.Id], replica.Id)
+			expanded.Services = append(expanded.Services, replica)
+		}
+	}
+
+	// Flows and latency constraints on unknown services are left to the analyzer
+	replicaIDs := func(serviceID string) []string {
+		if r, exists := ids[serviceID]; exists {
+			return r
+		}
+
+		return []string{serviceID}
+	}
+
+	expanded.Flows = make([]model.Flow, 0, len(application.Flows))
+	for _, f := range application.Flows {
+		dst := replicaIDs(f.Dst)
+
+		bandwidth := f.Bandwidth
+		if len(dst) > 1 {
+			bandwidth = (f.Bandwidth + len(dst) - 1) / len(dst)
+		}
+
+		for _, src := range replicaIDs(f.Src) {
+			for _, d := range dst {
+				expanded.Flows = append(expanded.Flows, model.Flow{
+					Src:       src,
+					Dst:       d,
+					Bandwidth: bandwidth,
+				})
+			}
+		}
+	}
+
+	expanded.MaxLatencies = make([]model.MaxLatencyDescription, 0, len(application.MaxLatencies))
+	for _, l := range application.MaxLatencies {
+		chains := [][]string{{}}
+		for _, serviceID := range l.Chain {
+			next := make([][]string, 0, len(chains)*len(replicaIDs(serviceID)))
+			for _, c := range chains {
+				for _, id := range replicaIDs(serviceID) {
+					next = append(next, append(append([]string{}, c...), id))
+				}
+			}
+			chains = next
+		}
+
+		for _, c := range chains {
+			expanded.MaxLatencies = append(expanded.MaxLatencies, model.MaxLatencyDescription{
+				Chain: c,
+				Value: l.Value,
+			})
+		}
+	}
+
+	// Returns the replica with the given index, or the first one if there are fewer replicas
+	replicaAt := func(ids []string, i int) string {
+		if i < len(ids) {
+			return ids[i]
+		}
+
+		return ids[0]
+	}
+
+	expanded.Affinities = make([]model.AffinityRule, 0, len(application.Affinities))
+	for _, r := range application.Affinities {
+		services := r.GetServices()
+
+		if r.IsCoLocation() {
+			// Replicas with the same index share a node, or follow the first replica of services with fewer replicas
+			for _, s := range services[1:] {
+				first, other := replicaIDs(services[0]), replicaIDs(s)
+				for i := 0; i < len(first) || i < len(other); i++ {
+					expanded.Affinities = append(expanded.Affinities, model.AffinityRule{
+						Type:     model.CoLocatedAffinity,
+						Services: []string{replicaAt(first, i), replicaAt(other, i)},
+					})
+				}
+			}
+			continue
+		}
+
+		// No replica of a service shares a node with a replica of another service of the rule
+		all := make([][]string, len(services))
+		for i, s := range services {
+			all[i] = replicaIDs(s)
+		}
+
+		for i := 0; i < len(all); i++ {
+			for j := i + 1; j < len(all); j++ {
+				for _, s1 := range all[i] {
+					for _, s2 := range all[j] {
+						expanded.Affinities = append(expanded.Affinities, model.AffinityRule{
+							Type:     model.NotCoLocatedAffinity,
+							Services: []string{s1, s2},
+						})
+					}
+				}
+			}
+		}
+	}
+
+	return &expanded, refs, nil
+}
+
+// Maps the assignments of the replicas back to the services of the application
+func collapseReplicas(placement *model.Placement, refs map[string]replicaRef) error {
+	for i := range placement.Assignments {
+		a := &placement.Assignments[i]
+
+		ref, exists := refs[a.ServiceID]
+		if !exists {
+			return fmt.Errorf("unknown service %s in placement", a.ServiceID)
+		}
+
+		a.ServiceID = ref.serviceID
+		a.Replica = ref.replica
+	}
+
+	return nil
+}
@@ -0,0 +1,272 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"foglute/internal/model"
+	"foglute/pkg/config"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"math"
+	"strings"
+)
+
+const (
+	millicoresPerCore = 1000
+	bytesPerGiB       = 1 << 30
+)
+
+// Reduces a set of resources to HW units using the given weights
+func hwUnits(weights config.ResourceWeights, r model.Resources) float64 {
+	units := weights.CPU*float64(r.CPU)/millicoresPerCore +
+		weights.Memory*float64(r.Memory)/bytesPerGiB +
+		weights.EphemeralStorage*float64(r.EphemeralStorage)/bytesPerGiB
+
+	for name, amount := range r.Extended {
+		units += weights.Extended[name] * float64(amount)
+	}
+
+	return units
+}
+
+// Returns the resources of a node that can be allocated to services
+func getNodeResources(node *apiv1.Node) model.Resources {
+	list := node.Status.Allocatable
+	if len(list) == 0 {
+		list = node.Status.Capacity
+	}
+
+	r := model.Resources{
+		CPU:              list.Cpu().MilliValue(),
+		Memory:           list.Memory().Value(),
+		EphemeralStorage: list.StorageEphemeral().Value(),
+		Extended:         make(map[string]int64),
+	}
+
+	for name, q := range list {
+		if isExtendedResource(name) {
+			r.Extended[string(name)] = q.Value()
+		}
+	}
+
+	return r
+}
+
+// Returns true if the resource is not a native Kubernetes resource
+func isExtendedResource(name apiv1.ResourceName) bool {
+	switch name {
+	case apiv1.ResourceCPU, apiv1.ResourceMemory, apiv1.ResourceEphemeralStorage, apiv1.ResourceStorage, apiv1.ResourcePods:
+		return false
+	}
+
+	// Extended resources are domain-prefixed, unlike hugepages-* and attachable volumes
+	return strings.Contains(string(name), "/")
+}
+
+// Returns the resources corresponding to a set of quantities
+func toResources(quantities config.ResourceQuantities) model.Resources {
+	r := model.Resources{
+		Extended: make(map[string]int64),
+	}
+
+	for name, q := range quantities {
+		switch apiv1.ResourceName(name) {
+		case apiv1.ResourceCPU:
+			r.CPU = q.MilliValue()
+		case apiv1.ResourceMemory:
+			r.Memory = q.Value()
+		case apiv1.ResourceEphemeralStorage:
+			r.EphemeralStorage = q.Value()
+		default:
+			r.Extended[name] = q.Value()
+		}
+	}
+
+	return r
+}
+
+// Returns true if the service or any of its images declare structured resources
+func hasStructuredResources(service *model.Service) bool {
+	if service.Resources != nil && !service.Resources.IsEmpty() {
+		return true
+	}
+
+	for _, image := range service.Images {
+		if image.Resources != nil && image.Resources.Requests != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the resources requested by each container of a service.
+// Images without explicit requests evenly share the service resources that are not requested by the other images,
+// up to their explicit limits.
+// The resources of a service are its structured resources or, if not set, its HW requirements times the HW unit.
+func getContainerRequests(service *model.Service, hwUnit model.Resources) []model.Resources {
+	total := model.Resources{Extended: make(map[string]int64)}
+	if service.Resources != nil && !service.Resources.IsEmpty() {
+		total = total.Add(*service.Resources)
+	} else {
+		total.CPU = hwUnit.CPU * int64(service.HWReqs)
+		total.Memory = hwUnit.Memory * int64(service.HWReqs)
+		total.EphemeralStorage = hwUnit.EphemeralStorage * int64(service.HWReqs)
+		for name, amount := range hwUnit.Extended {
+			total.Extended[name] = amount * int64(service.HWReqs)
+		}
+	}
+
+	requests := make([]model.Resources, len(service.Images))
+	explicit := model.Resources{Extended: make(map[string]int64)}
+	implicit := make([]int, 0)
+
+	for i, image := range service.Images {
+		if image.Resources != nil && image.Resources.Requests != nil {
+			requests[i] = model.Resources{}.Add(*image.Resources.Requests)
+			explicit = explicit.Add(*image.Resources.Requests)
+		} else {
+			implicit = append(implicit, i)
+		}
+	}
+
+	if len(implicit) == 0 {
+		return requests
+	}
+
+	share := func(t, e int64) int64 {
+		if t <= e {
+			return 0
+		}
+		return (t - e) / int64(len(implicit))
+	}
+
+	for n, i := range implicit {
+		requests[i] = model.Resources{
+			CPU:              share(total.CPU, explicit.CPU),
+			Memory:           share(total.Memory, explicit.Memory),
+			EphemeralStorage: share(total.EphemeralStorage, explicit.EphemeralStorage),
+			Extended:         make(map[string]int64),
+		}
+
+		// Extended resources are not divisible: the first image gets them all
+		if n == 0 {
+			for name, amount := range total.Extended {
+				if amount > explicit.Extended[name] {
+					requests[i].Extended[name] = amount - explicit.Extended[name]
+				}
+			}
+		}
+
+		if image := service.Images[i]; image.Resources != nil && image.Resources.Limits != nil {
+			requests[i] = clampToLimits(requests[i], *image.Resources.Limits)
+		}
+	}
+
+	return requests
+}
+
+// Returns requests that do not exceed a set of limits. Resources without a limit are not changed.
+func clampToLimits(requests model.Resources, limits model.Resources) model.Resources {
+	clamp := func(request, limit int64) int64 {
+		if limit > 0 && request > limit {
+			return limit
+		}
+		return request
+	}
+
+	clamped := model.Resources{
+		CPU:              clamp(requests.CPU, limits.CPU),
+		Memory:           clamp(requests.Memory, limits.Memory),
+		EphemeralStorage: clamp(requests.EphemeralStorage, limits.EphemeralStorage),
+		Extended:         make(map[string]int64),
+	}
+
+	for name, amount := range requests.Extended {
+		clamped.Extended[name] = clamp(amount, limits.Extended[name])
+	}
+
+	return clamped
+}
+
+// Returns a copy of the application in which the HW requirements of the services are expressed in HW units.
+// Services without structured resources keep their HW requirements.
+func prepareApplication(application *model.Application, weights config.ResourceWeights, hwUnit model.Resources) *model.Application {
+	prepared := *application
+	prepared.Services = make([]model.Service, len(application.Services))
+
+	for i, s := range application.Services {
+		if hasStructuredResources(&s) {
+			sum := model.Resources{}
+			for _, r := range getContainerRequests(&s, hwUnit) {
+				sum = sum.Add(r)
+			}
+
+			s.HWReqs = int(math.Ceil(hwUnits(weights, sum)))
+		}
+
+		prepared.Services[i] = s
+	}
+
+	return &prepared
+}
+
+// Returns Kubernetes resource requests and limits of a container.
+// If the image does not declare limits, they are set only for memory and extended resources, to avoid CPU throttling.
+func getResourceRequirements(image model.Image, requests model.Resources) apiv1.ResourceRequirements {
+	requirements := apiv1.ResourceRequirements{}
+	if requests.IsEmpty() && (image.Resources == nil || image.Resources.Limits == nil) {
+		return requirements
+	}
+
+	requirements.Requests = toResourceList(requests)
+
+	if image.Resources != nil && image.Resources.Limits != nil {
+		requirements.Limits = toResourceList(*image.Resources.Limits)
+	} else {
+		requirements.Limits = toResourceList(model.Resources{
+			Memory:   requests.Memory,
+			Extended: requests.Extended,
+		})
+	}
+
+	// Extended resources cannot be overcommitted: requests without an explicit limit are also the limits
+	for name, amount := range requests.Extended {
+		if _, limited := requirements.Limits[apiv1.ResourceName(name)]; !limited && amount > 0 {
+			requirements.Limits[apiv1.ResourceName(name)] = requirements.Requests[apiv1.ResourceName(name)]
+		}
+	}
+
+	return requirements
+}
+
+// Converts a set of resources to a Kubernetes resource list
+func toResourceList(r model.Resources) apiv1.ResourceList {
+	list := apiv1.ResourceList{}
+
+	if r.CPU > 0 {
+		list[apiv1.ResourceCPU] = *resource.NewMilliQuantity(r.CPU, resource.DecimalSI)
+	}
+
+	if r.Memory > 0 {
+		list[apiv1.ResourceMemory] = *resource.NewQuantity(r.Memory, resource.BinarySI)
+	}
+
+	if r.EphemeralStorage > 0 {
+		list[apiv1.ResourceEphemeralStorage] = *resource.NewQuantity(r.EphemeralStorage, resource.BinarySI)
+	}
+
+	for name, amount := range r.Extended {
+		if amount > 0 {
+			list[apiv1.ResourceName(name)] = *resource.NewQuantity(amount, resource.DecimalSI)
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,51 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"foglute/internal/model"
+	apiv1 "k8s.io/api/core/v1"
+	"testing"
+)
+
+func TestGetResourceRequirementsExtendedLimits(t *testing.T) {
+	const gpu = "nvidia.com/gpu"
+
+	withLimits := func(limits model.Resources) model.Image {
+		return model.Image{Resources: &model.ResourceRequirements{Limits: &limits}}
+	}
+
+	tests := []struct {
+		name     string
+		image    model.Image
+		requests model.Resources
+		limit    int64
+		limited  bool
+	}{
+		{"derived limit", model.Image{}, model.Resources{Extended: map[string]int64{gpu: 1}}, 1, true},
+		{"explicit limit", withLimits(model.Resources{Extended: map[string]int64{gpu: 2}}), model.Resources{Extended: map[string]int64{gpu: 1}}, 2, true},
+		{"explicit limit of other resources", withLimits(model.Resources{CPU: 500}), model.Resources{Extended: map[string]int64{gpu: 1}}, 1, true},
+		{"zero request", withLimits(model.Resources{CPU: 500}), model.Resources{Extended: map[string]int64{gpu: 0}}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requirements := getResourceRequirements(tt.image, tt.requests)
+
+			limit, limited := requirements.Limits[apiv1.ResourceName(gpu)]
+			if limited != tt.limited {
+				t.Fatalf("limit set = %v, want %v", limited, tt.limited)
+			}
+
+			if limited && limit.Value() != tt.limit {
+				t.Errorf("limit = %s, want %d", limit.String(), tt.limit)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"fmt"
+	"foglute/internal/model"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"log"
+	"time"
+)
+
+const (
+	// Delay between two checks of the pods of an application
+	rolloutPollInterval = 2 * time.Second
+)
+
+// Container waiting reasons that will not resolve without changing the application or its placement
+var rolloutFailureReasons = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CrashLoopBackOff":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+}
+
+// A ServiceStatus reports whether a service is running on the node it has been assigned to.
+type ServiceStatus struct {
+	ServiceID string `json:"service_id"`
+	Replica   int    `json:"replica"`
+	NodeName  string `json:"node_name"`
+	Cluster   string `json:"cluster,omitempty"`
+	Ready     bool   `json:"ready"`
+	Reason    string `json:"reason,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// Returns true if the service cannot become ready on its node
+func (s ServiceStatus) Failed() bool {
+	return !s.Ready && s.Reason != ""
+}
+
+// Waits until the services of an application are ready on their nodes, a service fails or the timeout expires.
+func (manager *Manager) waitForRollout(application *model.Application, placement *model.Placement, timeout time.Duration) []ServiceStatus {
+	log.Printf("Waiting for application %s to be ready...\n", application.ID)
+
+	namespace := manager.getNamespace(application)
+
+	statuses := make([]ServiceStatus, len(placement.Assignments))
+	for i, a := range placement.Assignments {
+		statuses[i] = ServiceStatus{
+			ServiceID: a.ServiceID,
+			Replica:   a.Replica,
+			NodeName:  a.NodeName,
+			Cluster:   a.Cluster,
+		}
+	}
+
+	err := wait.PollImmediate(rolloutPollInterval, timeout, func() (bool, error) {
+		done := true
+
+		for i := range statuses {
+			s := &statuses[i]
+			if s.Ready || s.Failed() {
+				continue
+			}
+
+			manager.updateServiceStatus(application, namespace, s)
+
+			if !s.Ready && !s.Failed() {
+				done = false
+			}
+		}
+
+		return done, nil
+	})
+
+	for i := range statuses {
+		s := &statuses[i]
+		if err == wait.ErrWaitTimeout && !s.Ready && !s.Failed() {
+			s.Reason = "Timeout"
+			s.Message = fmt.Sprintf("not ready after %v", timeout)
+		}
+
+		if s.Ready {
+			log.Printf("Service %s is ready on %s\n", s.ServiceID, s.NodeName)
+		} else {
+			log.Printf("Service %s is not ready on %s: %s %s\n", s.ServiceID, s.NodeName, s.Reason, s.Message)
+		}
+	}
+
+	return statuses
+}
+
+// Updates the status of a service from its pods
+func (manager *Manager) updateServiceStatus(application *model.Application, namespace string, status *ServiceStatus) {
+	selector := labels.SelectorFromSet(getReplicaSelector(application, status.ServiceID, status.Replica)).String()
+
+	view, err := manager.onCluster(status.Cluster)
+	if err != nil {
+		log.Printf("Cannot get pods of service %s: %s\n", status.ServiceID, err)
+		return
+	}
+
+	pods, err := view.clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		log.Printf("Cannot get pods of service %s: %s\n", status.ServiceID, err)
+		return
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+
+		// Skip pods of previous placements
+		if pod.Spec.NodeName != status.NodeName || pod.DeletionTimestamp != nil {
+			continue
+		}
+
+		if isPodReady(pod) {
+			status.Ready = true
+			status.Reason = ""
+			status.Message = ""
+			return
+		}
+
+		if pod.Status.Phase == apiv1.PodFailed {
+			status.Reason = pod.Status.Reason
+			status.Message = pod.Status.Message
+			continue
+		}
+
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.State.Waiting != nil && rolloutFailureReasons[cs.State.Waiting.Reason] {
+				status.Reason = cs.State.Waiting.Reason
+				status.Message = fmt.Sprintf("container %s: %s", cs.Name, cs.State.Waiting.Message)
+			}
+		}
+	}
+}
+
+// Returns true if the pod reports Ready condition
+func isPodReady(pod *apiv1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == apiv1.PodReady {
+			return cond.Status == apiv1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// Returns the errors of the services that are not ready
+func getRolloutErrors(statuses []ServiceStatus) []error {
+	errs := make([]error, 0)
+	for _, s := range statuses {
+		if !s.Ready {
+			errs = append(errs, fmt.Errorf("service %s not ready on %s: %s %s", s.ServiceID, s.NodeName, s.Reason, s.Message))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,252 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"fmt"
+	"foglute/internal/model"
+	"foglute/pkg/config"
+	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"log"
+	"sort"
+)
+
+// Returns the settings that enforce the security requirements of a service.
+// Requirements without a mapping are only taken into account by the placement.
+func getSecurityMapping(service *model.Service, mappings config.SecurityMappings) (config.SecurityMapping, error) {
+	merged := config.SecurityMapping{
+		Annotations: make(map[string]string),
+	}
+
+	isolation := make(map[string]bool)
+
+	for _, req := range service.SecReqs {
+		m, exists := mappings[req]
+		if !exists {
+			log.Printf("Security requirement %s of service %s has no mapping and is not enforced\n", req, service.Id)
+			continue
+		}
+
+		if m.RuntimeClass != "" {
+			if merged.RuntimeClass != "" && merged.RuntimeClass != m.RuntimeClass {
+				return merged, fmt.Errorf("service %s: security requirements need runtime classes %s and %s", service.Id, merged.RuntimeClass, m.RuntimeClass)
+			}
+			merged.RuntimeClass = m.RuntimeClass
+		}
+
+		if m.Security != nil {
+			security, err := mergeSecurityContexts(merged.Security, m.Security)
+			if err != nil {
+				return merged, fmt.Errorf("service %s: security requirements conflict: %s", service.Id, err)
+			}
+			merged.Security = security
+		}
+
+		for _, i := range m.Isolation {
+			if !isolation[i] {
+				isolation[i] = true
+				merged.Isolation = append(merged.Isolation, i)
+			}
+		}
+
+		for name, value := range m.Annotations {
+			if v, exists := merged.Annotations[name]; exists && v != value {
+				return merged, fmt.Errorf("service %s: security requirements need different values of annotation %s", service.Id, name)
+			}
+			merged.Annotations[name] = value
+		}
+	}
+
+	sort.Strings(merged.Isolation)
+
+	return merged, nil
+}
+
+// Combines two security contexts.
+// Settings with a single value, like the user, cannot differ between the two contexts, while restrictions of the
+// other context are always added, so that the other context is always enforced.
+func mergeSecurityContexts(base *model.SecurityContext, other *model.SecurityContext) (*model.SecurityContext, error) {
+	merged := model.SecurityContext{}
+	if base != nil {
+		merged = *base
+	}
+
+	if other.RunAsUser != nil {
+		if merged.RunAsUser != nil && *merged.RunAsUser != *other.RunAsUser {
+			return nil, fmt.Errorf("run_as_user %d differs from %d", *merged.RunAsUser, *other.RunAsUser)
+		}
+		merged.RunAsUser = other.RunAsUser
+	}
+
+	if other.RunAsGroup != nil {
+		if merged.RunAsGroup != nil && *merged.RunAsGroup != *other.RunAsGroup {
+			return nil, fmt.Errorf("run_as_group %d differs from %d", *merged.RunAsGroup, *other.RunAsGroup)
+		}
+		merged.RunAsGroup = other.RunAsGroup
+	}
+
+	if other.RunAsNonRoot != nil && (*other.RunAsNonRoot || merged.RunAsNonRoot == nil) {
+		merged.RunAsNonRoot = other.RunAsNonRoot
+	}
+
+	merged.ReadOnlyRootFilesystem = merged.ReadOnlyRootFilesystem || other.ReadOnlyRootFilesystem
+
+	if other.AllowPrivilegeEscalation != nil && (!*other.AllowPrivilegeEscalation || merged.AllowPrivilegeEscalation == nil) {
+		merged.AllowPrivilegeEscalation = other.AllowPrivilegeEscalation
+	}
+
+	merged.AddCapabilities = append(append([]string{}, merged.AddCapabilities...), other.AddCapabilities...)
+	merged.DropCapabilities = append(append([]string{}, merged.DropCapabilities...), other.DropCapabilities...)
+
+	if other.SeccompProfile != "" {
+		if merged.SeccompProfile != "" && merged.SeccompProfile != other.SeccompProfile {
+			return nil, fmt.Errorf("seccomp_profile %s differs from %s", merged.SeccompProfile, other.SeccompProfile)
+		}
+		merged.SeccompProfile = other.SeccompProfile
+	}
+
+	return &merged, nil
+}
+
+// Returns a copy of a service whose containers enforce the security settings of its requirements
+func enforceSecurity(service *model.Service, mapping config.SecurityMapping) (*model.Service, error) {
+	if mapping.Security == nil {
+		return service, nil
+	}
+
+	enforced := *service
+	enforced.Images = make([]model.Image, len(service.Images))
+
+	for i, image := range service.Images {
+		security, err := mergeSecurityContexts(image.Security, mapping.Security)
+		if err != nil {
+			return nil, fmt.Errorf("service %s, image %s: security settings conflict with the security requirements: %s", service.Id, image.Name, err)
+		}
+		image.Security = security
+
+		s := image.Security
+		if s.RunAsNonRoot != nil && *s.RunAsNonRoot && s.RunAsUser != nil && *s.RunAsUser == 0 {
+			return nil, fmt.Errorf("service %s, image %s: security requirements need a non-root user", service.Id, image.Name)
+		}
+
+		if s.AllowPrivilegeEscalation != nil && !*s.AllowPrivilegeEscalation && image.Privileged {
+			return nil, fmt.Errorf("service %s, image %s: security requirements forbid privileged containers", service.Id, image.Name)
+		}
+
+		enforced.Images[i] = image
+	}
+
+	return &enforced, nil
+}
+
+// Returns the name of the NetworkPolicy that isolates the pods of a service
+func getIsolationPolicyName(application *model.Application, serviceID string) string {
+	return makeName(false, application.ID, serviceID, "isolation")
+}
+
+// Returns the ports of a service that are reachable from outside the application
+func getPublicPorts(application *model.Application, service *model.Service) []networkingv1.NetworkPolicyPort {
+	routed := make(map[string]bool)
+	for _, r := range application.Routes {
+		if r.Service == service.Id {
+			routed[r.Port] = true
+		}
+	}
+
+	ports := make([]networkingv1.NetworkPolicyPort, 0)
+	for _, image := range service.Images {
+		for _, p := range image.Ports {
+			t := p.GetServiceType()
+			if t != model.NodePortService && t != model.LoadBalancerService && p.HostPort == 0 && !routed[p.Name] {
+				continue
+			}
+
+			protocol := apiv1.Protocol(p.GetProtocol())
+			port := intstr.FromInt(p.ContainerPort)
+			ports = append(ports, networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &port})
+		}
+	}
+
+	return ports
+}
+
+// Returns the NetworkPolicies that isolate the pods of the services whose security requirements ask for it.
+// Isolated pods can talk with all the pods of their application only if allowApplication is true, otherwise the
+// traffic within the application must be allowed by other policies.
+func getIsolationPolicies(application *model.Application, mappings config.SecurityMappings, allowApplication bool) []*networkingv1.NetworkPolicy {
+	policies := make([]*networkingv1.NetworkPolicy, 0)
+
+	appPeer := networkingv1.NetworkPolicyPeer{
+		PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{
+			config.AppLabel: labelValue(application.ID),
+		}},
+	}
+
+	for i := range application.Services {
+		s := &application.Services[i]
+
+		mapping, err := getSecurityMapping(s, mappings)
+		if err != nil || len(mapping.Isolation) == 0 {
+			continue
+		}
+
+		policy := &networkingv1.NetworkPolicy{
+			ObjectMeta: getObjectMeta(application, s.Id, getIsolationPolicyName(application, s.Id), ""),
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: getSelector(application, s.Id)},
+			},
+		}
+
+		for _, isolation := range mapping.Isolation {
+			switch isolation {
+			case config.IsolateIngress:
+				policy.Spec.PolicyTypes = append(policy.Spec.PolicyTypes, networkingv1.PolicyTypeIngress)
+				policy.Spec.Ingress = []networkingv1.NetworkPolicyIngressRule{}
+				if allowApplication {
+					policy.Spec.Ingress = append(policy.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{
+						From: []networkingv1.NetworkPolicyPeer{appPeer},
+					})
+				}
+
+				if ports := getPublicPorts(application, s); len(ports) > 0 {
+					policy.Spec.Ingress = append(policy.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{Ports: ports})
+				}
+			case config.IsolateEgress:
+				policy.Spec.PolicyTypes = append(policy.Spec.PolicyTypes, networkingv1.PolicyTypeEgress)
+				policy.Spec.Egress = []networkingv1.NetworkPolicyEgressRule{
+					{Ports: getDNSPorts()},
+				}
+				if allowApplication {
+					policy.Spec.Egress = append(policy.Spec.Egress, networkingv1.NetworkPolicyEgressRule{
+						To: []networkingv1.NetworkPolicyPeer{appPeer},
+					})
+				}
+			}
+		}
+
+		policies = append(policies, policy)
+	}
+
+	return policies
+}
+
+// Returns the ports of DNS servers, always reachable by isolated pods
+func getDNSPorts() []networkingv1.NetworkPolicyPort {
+	udp := apiv1.ProtocolUDP
+	tcp := apiv1.ProtocolTCP
+	port := intstr.FromInt(53)
+
+	return []networkingv1.NetworkPolicyPort{
+		{Protocol: &udp, Port: &port},
+		{Protocol: &tcp, Port: &port},
+	}
+}
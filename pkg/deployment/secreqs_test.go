@@ -0,0 +1,79 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"foglute/internal/model"
+	"foglute/pkg/config"
+	"testing"
+)
+
+func TestEnforceSecurity(t *testing.T) {
+	user := func(id int64) *int64 { return &id }
+
+	mapping := config.SecurityMapping{Security: &model.SecurityContext{
+		RunAsUser:      user(1000),
+		SeccompProfile: model.SeccompRuntimeDefault,
+	}}
+
+	tests := []struct {
+		name     string
+		security *model.SecurityContext
+		user     int64
+		seccomp  string
+		fails    bool
+	}{
+		{"no settings", nil, 1000, model.SeccompRuntimeDefault, false},
+		{"same settings", &model.SecurityContext{RunAsUser: user(1000), SeccompProfile: model.SeccompRuntimeDefault}, 1000, model.SeccompRuntimeDefault, false},
+		{"other settings", &model.SecurityContext{ReadOnlyRootFilesystem: true}, 1000, model.SeccompRuntimeDefault, false},
+		{"other user", &model.SecurityContext{RunAsUser: user(0)}, 0, "", true},
+		{"other seccomp profile", &model.SecurityContext{SeccompProfile: model.SeccompUnconfined}, 0, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &model.Service{Id: "s", Images: []model.Image{{Name: "app", Security: tt.security}}}
+
+			enforced, err := enforceSecurity(service, mapping)
+			if tt.fails {
+				if err == nil {
+					t.Errorf("no error, want one")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			s := enforced.Images[0].Security
+			if s.RunAsUser == nil || *s.RunAsUser != tt.user || s.SeccompProfile != tt.seccomp {
+				t.Errorf("security = %+v, want user %d and seccomp profile %s", s, tt.user, tt.seccomp)
+			}
+		})
+	}
+}
+
+func TestGetSecurityMappingConflicts(t *testing.T) {
+	user := func(id int64) *int64 { return &id }
+
+	mappings := config.SecurityMappings{
+		"a": {Security: &model.SecurityContext{RunAsUser: user(1000)}},
+		"b": {Security: &model.SecurityContext{RunAsUser: user(1000), ReadOnlyRootFilesystem: true}},
+		"c": {Security: &model.SecurityContext{RunAsUser: user(2000)}},
+	}
+
+	if _, err := getSecurityMapping(&model.Service{Id: "s", SecReqs: []string{"a", "b"}}, mappings); err != nil {
+		t.Errorf("unexpected error for compatible requirements: %s", err)
+	}
+
+	if _, err := getSecurityMapping(&model.Service{Id: "s", SecReqs: []string{"a", "c"}}, mappings); err == nil {
+		t.Errorf("no error for requirements with different users")
+	}
+}
@@ -0,0 +1,253 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"fmt"
+	"foglute/internal/model"
+	"foglute/pkg/config"
+	apiv1 "k8s.io/api/core/v1"
+	"path"
+	"strings"
+)
+
+const (
+	// Prefix of the pod annotations that set the seccomp profile of a container
+	seccompContainerAnnotationPrefix = "container.seccomp.security.alpha.kubernetes.io/"
+)
+
+// Kubernetes values of the seccomp profiles
+var seccompProfiles = map[string]string{
+	model.SeccompRuntimeDefault: "runtime/default",
+	model.SeccompUnconfined:     "unconfined",
+}
+
+// Returns the security context of a container.
+// Containers that are not privileged run as non-root users if the policy requires it.
+func getSecContext(image model.Image, policy config.SecurityPolicy) *apiv1.SecurityContext {
+	tt := true
+	secContext := &apiv1.SecurityContext{}
+	// Set privileged mode
+	if image.Privileged {
+		secContext.Privileged = &tt
+	} else if policy.RequireNonRoot {
+		secContext.RunAsNonRoot = &tt
+	}
+
+	s := image.Security
+	if s == nil {
+		return secContext
+	}
+
+	secContext.RunAsUser = s.RunAsUser
+	secContext.RunAsGroup = s.RunAsGroup
+	if s.RunAsNonRoot != nil {
+		secContext.RunAsNonRoot = s.RunAsNonRoot
+	}
+
+	if s.ReadOnlyRootFilesystem {
+		secContext.ReadOnlyRootFilesystem = &tt
+	}
+
+	secContext.AllowPrivilegeEscalation = s.AllowPrivilegeEscalation
+
+	if len(s.AddCapabilities) > 0 || len(s.DropCapabilities) > 0 {
+		secContext.Capabilities = &apiv1.Capabilities{
+			Add:  toCapabilities(s.AddCapabilities),
+			Drop: toCapabilities(s.DropCapabilities),
+		}
+	}
+
+	return secContext
+}
+
+func toCapabilities(names []string) []apiv1.Capability {
+	capabilities := make([]apiv1.Capability, len(names))
+	for i, name := range names {
+		capabilities[i] = apiv1.Capability(model.NormalizeCapability(name))
+	}
+
+	return capabilities
+}
+
+// Returns the pod annotations that set the seccomp profiles of the containers of a service
+func getSeccompAnnotations(service *model.Service, containers []apiv1.Container) map[string]string {
+	annotations := make(map[string]string)
+
+	for i, image := range service.Images {
+		if image.Security == nil || image.Security.SeccompProfile == "" {
+			continue
+		}
+
+		profile, known := seccompProfiles[image.Security.SeccompProfile]
+		if !known {
+			profile = image.Security.SeccompProfile
+		}
+
+		annotations[seccompContainerAnnotationPrefix+containers[i].Name] = profile
+	}
+
+	return annotations
+}
+
+// Returns the volumes of the node devices used by the containers of a service, and the volume mounts of each container.
+// A device missing on the node makes the pod fail instead of mounting an empty directory.
+func getDeviceVolumes(service *model.Service) ([]apiv1.Volume, [][]apiv1.VolumeMount) {
+	volumes := make([]apiv1.Volume, 0)
+	mounts := make([][]apiv1.VolumeMount, len(service.Images))
+	volumeNames := make(map[string]string)
+
+	for i, image := range service.Images {
+		for _, d := range image.Devices {
+			hostPath := path.Clean(d.HostPath)
+
+			// Containers using the same device share its volume
+			name, exists := volumeNames[hostPath]
+			if !exists {
+				name = makeName(false, "device", hostPath)
+				volumeNames[hostPath] = name
+
+				hostPathType := apiv1.HostPathCharDev
+				if d.Block {
+					hostPathType = apiv1.HostPathBlockDev
+				}
+
+				volumes = append(volumes, apiv1.Volume{
+					Name: name,
+					VolumeSource: apiv1.VolumeSource{
+						HostPath: &apiv1.HostPathVolumeSource{Path: hostPath, Type: &hostPathType},
+					},
+				})
+			}
+
+			mounts[i] = append(mounts[i], apiv1.VolumeMount{
+				Name:      name,
+				MountPath: d.GetPath(),
+				ReadOnly:  d.ReadOnly,
+			})
+		}
+	}
+
+	return volumes, mounts
+}
+
+// Suffix of the allowed host paths that containers can write
+const writableHostPathSuffix = ":rw"
+
+// Returns true if a host path can be mounted according to a list of allowed path prefixes.
+// Writable mounts need a prefix with the :rw suffix.
+func isAllowedHostPath(allowed []string, hostPath string, writable bool) bool {
+	hostPath = path.Clean(hostPath)
+
+	for _, entry := range allowed {
+		prefix := strings.TrimSuffix(entry, writableHostPathSuffix)
+		if writable && prefix == entry {
+			continue
+		}
+
+		prefix = path.Clean(prefix)
+		if hostPath == prefix || strings.HasPrefix(hostPath, strings.TrimSuffix(prefix, "/")+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns true if a container of a service mounts a volume without the read-only flag
+func isWritableVolume(service *model.Service, volume string) bool {
+	for _, image := range service.Images {
+		for _, m := range image.VolumeMounts {
+			if m.Volume == volume && !m.ReadOnly {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Checks that the containers of an application do not request privileges beyond the security policy
+func checkSecurityPolicy(application *model.Application, policy config.SecurityPolicy) error {
+	allowedCapabilities := make(map[string]bool)
+	for _, c := range policy.AllowedCapabilities {
+		allowedCapabilities[model.NormalizeCapability(c)] = true
+	}
+
+	isAllowedDevice := func(device string) bool {
+		for _, pattern := range policy.AllowedDevices {
+			if matched, _ := path.Match(pattern, device); matched {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for i := range application.Services {
+		s := &application.Services[i]
+
+		for _, v := range s.Volumes {
+			if v.Type != model.HostPathVolume {
+				continue
+			}
+
+			writable := isWritableVolume(s, v.Name)
+			if !isAllowedHostPath(policy.AllowedHostPaths, v.Path, writable) {
+				if writable && isAllowedHostPath(policy.AllowedHostPaths, v.Path, false) {
+					return fmt.Errorf("service %s: host path %s of volume %s can only be mounted read-only", s.Id, v.Path, v.Name)
+				}
+
+				return fmt.Errorf("service %s: host path %s of volume %s is not allowed", s.Id, v.Path, v.Name)
+			}
+		}
+
+		for _, image := range s.Images {
+			if image.Privileged && !policy.AllowPrivileged {
+				return fmt.Errorf("service %s, image %s: privileged containers are not allowed", s.Id, image.Name)
+			}
+
+			for _, d := range image.Devices {
+				if !isAllowedDevice(path.Clean(d.HostPath)) {
+					return fmt.Errorf("service %s, image %s: device %s is not allowed", s.Id, image.Name, d.HostPath)
+				}
+			}
+
+			if image.Security == nil {
+				continue
+			}
+
+			if policy.RequireNonRoot && !image.Privileged && image.RequestsRoot() {
+				return fmt.Errorf("service %s, image %s: containers must run as non-root users", s.Id, image.Name)
+			}
+
+			for _, c := range image.Security.AddCapabilities {
+				name := model.NormalizeCapability(c)
+				if !allowedCapabilities[name] && !allowedCapabilities["ALL"] {
+					return fmt.Errorf("service %s, image %s: capability %s is not allowed", s.Id, image.Name, name)
+				}
+			}
+
+			if image.Security.SeccompProfile == model.SeccompUnconfined && !policy.AllowUnconfined {
+				return fmt.Errorf("service %s, image %s: unconfined seccomp profile is not allowed", s.Id, image.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Checks that an application complies with the security policy of FogLute
+func (manager *Manager) CheckSecurityPolicy(application *model.Application) error {
+	if err := checkSecurityPolicy(application, manager.config.SecurityPolicy); err != nil {
+		return fmt.Errorf("rejected by the security policy: %s", err)
+	}
+
+	return nil
+}
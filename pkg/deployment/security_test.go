@@ -0,0 +1,64 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"foglute/internal/model"
+	apiv1 "k8s.io/api/core/v1"
+	"testing"
+)
+
+func TestGetDeviceVolumes(t *testing.T) {
+	service := &model.Service{
+		Id: "sensor",
+		Images: []model.Image{
+			{Name: "reader", Privileged: true, Devices: []model.Device{
+				{HostPath: "/dev/ttyUSB0"},
+				{HostPath: "/dev/sda1", Path: "/dev/disk", Block: true},
+			}},
+			{Name: "writer", Privileged: true, Devices: []model.Device{
+				{HostPath: "/dev/./ttyUSB0", ReadOnly: true},
+			}},
+		},
+	}
+
+	volumes, mounts := getDeviceVolumes(service)
+
+	want := map[string]apiv1.HostPathType{
+		"/dev/ttyUSB0": apiv1.HostPathCharDev,
+		"/dev/sda1":    apiv1.HostPathBlockDev,
+	}
+
+	if len(volumes) != len(want) {
+		t.Fatalf("%d volumes, want %d", len(volumes), len(want))
+	}
+
+	for _, v := range volumes {
+		hostPath := v.HostPath
+		if hostPath == nil || hostPath.Type == nil {
+			t.Fatalf("volume %s has no host path type", v.Name)
+		}
+
+		if *hostPath.Type != want[hostPath.Path] {
+			t.Errorf("host path type of %s = %q, want %q", hostPath.Path, *hostPath.Type, want[hostPath.Path])
+		}
+	}
+
+	if len(mounts[0]) != 2 || len(mounts[1]) != 1 {
+		t.Fatalf("mounts = %v, want 2 and 1", mounts)
+	}
+
+	if mounts[1][0].Name != mounts[0][0].Name || !mounts[1][0].ReadOnly {
+		t.Errorf("mount of the shared device = %v, want a read-only mount of %s", mounts[1][0], mounts[0][0].Name)
+	}
+
+	if mounts[0][1].MountPath != "/dev/disk" {
+		t.Errorf("mount path = %s, want /dev/disk", mounts[0][1].MountPath)
+	}
+}
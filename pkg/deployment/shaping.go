@@ -0,0 +1,99 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"fmt"
+	"foglute/internal/model"
+)
+
+const (
+	// Pod annotations read by the CNI bandwidth plugin
+	ingressBandwidthAnnotation = "kubernetes.io/ingress-bandwidth"
+	egressBandwidthAnnotation  = "kubernetes.io/egress-bandwidth"
+)
+
+// A ServiceShaping reports the traffic limits of the pods of a service, in bits per second.
+// Empty limits mean that the traffic is not limited.
+type ServiceShaping struct {
+	ServiceID string `json:"service_id"`
+	Ingress   string `json:"ingress,omitempty"`
+	Egress    string `json:"egress,omitempty"`
+}
+
+// Returns a bandwidth of flows, in Mbps, as a value of the bandwidth annotations
+func formatBandwidth(mbps int) string {
+	if mbps <= 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%dM", mbps)
+}
+
+// Returns the traffic limits of the services of an application.
+// Each replica of a service sends the bandwidth of the outbound flows of the service, and receives its share of the
+// bandwidth of the inbound flows sent by each replica of their sources.
+func getShaping(application *model.Application) []ServiceShaping {
+	shaping := make([]ServiceShaping, len(application.Services))
+
+	for i := range application.Services {
+		s := &application.Services[i]
+
+		egress, ingress := 0, 0
+		for _, f := range application.Flows {
+			if f.Src == s.Id {
+				egress += f.Bandwidth
+			}
+
+			if f.Dst == s.Id {
+				src, _ := application.GetService(f.Src)
+				share := (f.Bandwidth + s.GetReplicas() - 1) / s.GetReplicas()
+				ingress += share * src.GetReplicas()
+			}
+		}
+
+		shaping[i] = ServiceShaping{
+			ServiceID: s.Id,
+			Ingress:   formatBandwidth(ingress),
+			Egress:    formatBandwidth(egress),
+		}
+	}
+
+	return shaping
+}
+
+// Returns the traffic limits of a service, if bandwidth shaping is enabled
+func (manager *Manager) getServiceShaping(application *model.Application, serviceID string) (ServiceShaping, bool) {
+	if !manager.config.BandwidthShaping {
+		return ServiceShaping{}, false
+	}
+
+	for _, s := range getShaping(application) {
+		if s.ServiceID == serviceID {
+			return s, true
+		}
+	}
+
+	return ServiceShaping{}, false
+}
+
+// Returns the pod annotations that apply the traffic limits of a service
+func getShapingAnnotations(shaping ServiceShaping) map[string]string {
+	annotations := make(map[string]string)
+
+	if shaping.Ingress != "" {
+		annotations[ingressBandwidthAnnotation] = shaping.Ingress
+	}
+
+	if shaping.Egress != "" {
+		annotations[egressBandwidthAnnotation] = shaping.Egress
+	}
+
+	return annotations
+}
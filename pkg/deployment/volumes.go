@@ -0,0 +1,149 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"foglute/internal/model"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"log"
+	"strconv"
+)
+
+const (
+	// Annotation of a PersistentVolumeClaim that tells the volume provisioner the node of the pod that uses it.
+	// It is normally set by the scheduler, which pods bound to their node with nodeName skip.
+	selectedNodeAnnotation = "volume.kubernetes.io/selected-node"
+)
+
+// Returns the name of the PersistentVolumeClaim of a persistent volume of a replica of a service.
+// Each replica has its own claims.
+func getClaimName(application *model.Application, serviceID string, replica int, volume *model.Volume) string {
+	if replica == 0 {
+		return makeName(false, application.ID, serviceID, volume.Name)
+	}
+
+	return makeName(false, application.ID, serviceID, volume.Name, "replica", strconv.Itoa(replica))
+}
+
+// Returns the PersistentVolumeClaims of the persistent volumes of the placed services of an application.
+// Claims are bound to the node of their replica, so that volumes of WaitForFirstConsumer storage classes are provisioned.
+func getPersistentVolumeClaims(application *model.Application, placement *model.Placement) []*apiv1.PersistentVolumeClaim {
+	claims := make([]*apiv1.PersistentVolumeClaim, 0)
+
+	for _, a := range placement.Assignments {
+		s, exists := application.GetService(a.ServiceID)
+		if !exists {
+			continue
+		}
+
+		for j := range s.Volumes {
+			v := &s.Volumes[j]
+			if v.Type != model.PersistentVolume {
+				continue
+			}
+
+			claim := &apiv1.PersistentVolumeClaim{
+				ObjectMeta: getObjectMeta(application, s.Id, getClaimName(application, s.Id, a.Replica, v), v.Name),
+				Spec: apiv1.PersistentVolumeClaimSpec{
+					AccessModes: []apiv1.PersistentVolumeAccessMode{apiv1.ReadWriteOnce},
+					Resources: apiv1.ResourceRequirements{
+						Requests: apiv1.ResourceList{
+							apiv1.ResourceStorage: *resource.NewQuantity(v.Size, resource.BinarySI),
+						},
+					},
+				},
+			}
+
+			claim.Annotations[selectedNodeAnnotation] = a.NodeName
+
+			if v.StorageClass != "" {
+				storageClass := v.StorageClass
+				claim.Spec.StorageClassName = &storageClass
+			}
+
+			claims = append(claims, claim)
+		}
+	}
+
+	return claims
+}
+
+// Returns the volumes of a replica of a service and the volume mounts of each of its containers
+func getServiceVolumes(application *model.Application, service *model.Service, replica int) ([]apiv1.Volume, [][]apiv1.VolumeMount) {
+	volumes := make([]apiv1.Volume, len(service.Volumes))
+	names := make(map[string]string)
+
+	for i := range service.Volumes {
+		v := &service.Volumes[i]
+
+		volumes[i].Name = makeName(false, "volume", v.Name)
+		names[v.Name] = volumes[i].Name
+
+		switch v.Type {
+		case model.EmptyDirVolume:
+			volumes[i].EmptyDir = &apiv1.EmptyDirVolumeSource{}
+			if v.Size > 0 {
+				volumes[i].EmptyDir.SizeLimit = resource.NewQuantity(v.Size, resource.BinarySI)
+			}
+		case model.HostPathVolume:
+			hostPathType := apiv1.HostPathDirectoryOrCreate
+			volumes[i].HostPath = &apiv1.HostPathVolumeSource{
+				Path: v.Path,
+				Type: &hostPathType,
+			}
+		case model.PersistentVolume:
+			volumes[i].PersistentVolumeClaim = &apiv1.PersistentVolumeClaimVolumeSource{
+				ClaimName: getClaimName(application, service.Id, replica, v),
+			}
+		}
+	}
+
+	mounts := make([][]apiv1.VolumeMount, len(service.Images))
+	for i, image := range service.Images {
+		for _, m := range image.VolumeMounts {
+			mounts[i] = append(mounts[i], apiv1.VolumeMount{
+				Name:      names[m.Volume],
+				MountPath: m.Path,
+				ReadOnly:  m.ReadOnly,
+			})
+		}
+	}
+
+	return volumes, mounts
+}
+
+// Returns the node that holds the data of a replica of a service, that is the node of its current Deployment.
+// It returns an empty string if the service has no persistent volumes or the replica is not deployed.
+func (manager *Manager) getDataNode(application *model.Application, service *model.Service, replica int) string {
+	if !service.HasPersistentVolumes() {
+		return ""
+	}
+
+	namespace := manager.getNamespace(application)
+
+	for _, c := range manager.clusters {
+		deployment, err := c.clientset.AppsV1().Deployments(namespace).Get(getDeploymentName(application, service.Id, replica), metav1.GetOptions{})
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				log.Printf("Cannot get the node of the data of service %s: %s\n", service.Id, err)
+			}
+
+			continue
+		}
+
+		if isOwnedBy(deployment, application) {
+			return deployment.Spec.Template.Spec.NodeName
+		}
+	}
+
+	return ""
+}
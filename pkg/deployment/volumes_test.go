@@ -0,0 +1,56 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package deployment
+
+import (
+	"foglute/internal/model"
+	"testing"
+)
+
+func TestGetPersistentVolumeClaimsSelectedNode(t *testing.T) {
+	application := &model.Application{
+		ID: "app",
+		Services: []model.Service{
+			{Id: "db", Volumes: []model.Volume{
+				{Name: "data", Type: model.PersistentVolume, Size: 1 << 30, StorageClass: "local-path"},
+				{Name: "cache", Type: model.EmptyDirVolume},
+			}},
+			{Id: "web"},
+		},
+	}
+
+	placement := &model.Placement{Assignments: []model.Assignment{
+		{ServiceID: "db", NodeName: "node-1", Replica: 0},
+		{ServiceID: "db", NodeName: "node-2", Replica: 1},
+		{ServiceID: "web", NodeName: "node-3"},
+	}}
+
+	claims := getPersistentVolumeClaims(application, placement)
+
+	want := map[string]string{
+		getClaimName(application, "db", 0, &application.Services[0].Volumes[0]): "node-1",
+		getClaimName(application, "db", 1, &application.Services[0].Volumes[0]): "node-2",
+	}
+
+	if len(claims) != len(want) {
+		t.Fatalf("%d claims, want %d", len(claims), len(want))
+	}
+
+	for _, c := range claims {
+		node, exists := want[c.Name]
+		if !exists {
+			t.Errorf("unexpected claim %s", c.Name)
+			continue
+		}
+
+		if got := c.Annotations[selectedNodeAnnotation]; got != node {
+			t.Errorf("claim %s selected node = %q, want %q", c.Name, got, node)
+		}
+	}
+}
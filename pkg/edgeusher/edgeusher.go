@@ -81,6 +81,7 @@ func getCode(app *model.Application, infr *model.Infrastructure, execPath string
 
 	// Make placements
 	placements := make([]string, len(app.Services))
+	nodeTerms := make(map[string]string)
 
 	for i, service := range app.Services {
 		n := service.NodeName
@@ -89,11 +90,22 @@ func getCode(app *model.Application, infr *model.Infrastructure, execPath string
 		}
 
 		placements[i] = fmt.Sprintf("on(%s, %s)", service.Id, n)
+		nodeTerms[service.Id] = n
 	}
 
 	placementsCode := "[" + strings.Join(placements, ",") + "]"
 
-	return appProlog + "\n" + infrProlog + "\n\n:- consult('" + execPath + "').\nquery(placement(Chain, " + placementsCode + ", Routes)).\n"
+	code := appProlog + "\n" + infrProlog + "\n\n:- consult('" + execPath + "').\n"
+
+	constraintsCode, goals := getPlConstraints(app, infr, nodeTerms)
+	if len(goals) == 0 {
+		return code + "query(placement(Chain, " + placementsCode + ", Routes)).\n"
+	}
+
+	// Wrap EdgeUsher placements with the constraints that it does not handle
+	return code + constraintsCode + "\n" +
+		"constrained_placement(Chain, " + placementsCode + ", Routes) :- placement(Chain, " + placementsCode + ", Routes), " + strings.Join(goals, ", ") + ".\n" +
+		"query(constrained_placement(Chain, " + placementsCode + ", Routes)).\n"
 }
 
 // Converts all strings in placements to get real names for services and nodes using symbol tables.
@@ -142,6 +154,49 @@ func cleanApp(application *model.Application, table *SymbolTable) *model.Applica
 		for ir, r := range s.SecReqs {
 			c.SecReqs[ir] = table.Add(r)
 		}
+
+		c.Tolerations = s.Tolerations
+		c.Volumes = s.Volumes
+		c.Spread = s.Spread
+		if s.ReplicaOf != "" {
+			c.ReplicaOf = table.Add(s.ReplicaOf)
+		}
+		c.ExcludedNodes = make([]string, len(s.ExcludedNodes))
+		for ie, n := range s.ExcludedNodes {
+			c.ExcludedNodes[ie] = table.Add(n)
+		}
+
+		c.LocationConstraints = make([]model.LocationConstraint, len(s.LocationConstraints))
+		for ic, lc := range s.LocationConstraints {
+			cc := &c.LocationConstraints[ic]
+
+			cc.Type = lc.Type
+			cc.Center = lc.Center
+			cc.Radius = lc.Radius
+			if lc.Zone != "" {
+				cc.Zone = table.Add(lc.Zone)
+			}
+			if lc.Service != "" {
+				cc.Service = table.Add(lc.Service)
+			}
+		}
+	}
+
+	cleaned.Affinities = make([]model.AffinityRule, len(application.Affinities))
+	for ir, r := range application.Affinities {
+		c := &cleaned.Affinities[ir]
+
+		c.Type = r.Type
+		c.Services = make([]string, len(r.Services))
+		for is, s := range r.Services {
+			c.Services[is] = table.Add(s)
+		}
+		if r.Service != "" {
+			c.Service = table.Add(r.Service)
+		}
+		if r.Target != "" {
+			c.Target = table.Add(r.Target)
+		}
 	}
 
 	for idf, f := range application.Flows {
@@ -182,6 +237,11 @@ func cleanInfrastructure(infrastructure *model.Infrastructure, table *SymbolTabl
 		c.Name = table.Add(node.Name)
 		c.Address = table.Add(node.Address)
 		c.Location = node.Location
+		c.Taints = node.Taints
+		c.StorageClasses = node.StorageClasses
+		if node.Location.Zone != "" {
+			c.Location.Zone = table.Add(node.Location.Zone)
+		}
 
 		c.Profiles = make([]model.NodeProfile, len(node.Profiles))
 
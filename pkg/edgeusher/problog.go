@@ -66,6 +66,116 @@ func getPlCodeFromInfrastructure(infrastructure *model.Infrastructure) string {
 	return fmt.Sprintf("%%%% Infrastructure: %s\n%s\n%s", "kube_infrastructure", strings.Join(nodesCode, "\n"), strings.Join(linksCode, "\n"))
 }
 
+// Returns Problog facts and goals that restrict the nodes on which the services of an application can be placed.
+// nodeTerms maps each service to the term used for its node in the placement query.
+func getPlConstraints(application *model.Application, infrastructure *model.Infrastructure, nodeTerms map[string]string) (string, []string) {
+	facts := make([]string, 0)
+	goals := make([]string, 0)
+
+	for i := range application.Services {
+		s := &application.Services[i]
+
+		eligible := make([]string, 0)
+		for j := range infrastructure.Nodes {
+			n := &infrastructure.Nodes[j]
+
+			if s.CanRunOn(n) {
+				eligible = append(eligible, fmt.Sprintf("eligible(%s, %s).", s.Id, n.Name))
+			}
+		}
+
+		if len(eligible) == 0 {
+			return "", []string{"fail"}
+		}
+
+		// Services that are not restricted need no eligibility facts
+		if len(eligible) < len(infrastructure.Nodes) {
+			facts = append(facts, eligible...)
+			goals = append(goals, fmt.Sprintf("eligible(%s, %s)", s.Id, nodeTerms[s.Id]))
+		}
+
+		for _, c := range s.LocationConstraints {
+			if c.Type == model.SameZoneConstraint {
+				goals = append(goals, fmt.Sprintf("same_zone(%s, %s)", nodeTerms[s.Id], nodeTerms[c.Service]))
+			}
+		}
+	}
+
+	goals = append(goals, getPlSpreadGoals(application, nodeTerms)...)
+	goals = append(goals, getPlAffinityGoals(application, nodeTerms)...)
+
+	sameZone := make([]string, 0)
+	for _, n1 := range infrastructure.Nodes {
+		for _, n2 := range infrastructure.Nodes {
+			if n1.Location.Zone != "" && n1.Location.Zone == n2.Location.Zone {
+				sameZone = append(sameZone, fmt.Sprintf("same_zone(%s, %s).", n1.Name, n2.Name))
+			}
+		}
+	}
+
+	return fmt.Sprintf("%%%% Constraints\n%s\n%s\n", strings.Join(facts, "\n"), strings.Join(sameZone, "\n")), goals
+}
+
+// Returns Problog goals that spread the replicas of the services over distinct nodes or zones
+func getPlSpreadGoals(application *model.Application, nodeTerms map[string]string) []string {
+	goals := make([]string, 0)
+
+	replicas := make(map[string][]*model.Service)
+	groups := make([]string, 0)
+	for i := range application.Services {
+		s := &application.Services[i]
+		if s.ReplicaOf == "" {
+			continue
+		}
+
+		if _, exists := replicas[s.ReplicaOf]; !exists {
+			groups = append(groups, s.ReplicaOf)
+		}
+		replicas[s.ReplicaOf] = append(replicas[s.ReplicaOf], s)
+	}
+
+	for _, group := range groups {
+		r := replicas[group]
+
+		for i := 0; i < len(r); i++ {
+			for j := i + 1; j < len(r); j++ {
+				switch r[i].GetSpread() {
+				case model.SpreadNodes:
+					goals = append(goals, fmt.Sprintf("%s \\= %s", nodeTerms[r[i].Id], nodeTerms[r[j].Id]))
+				case model.SpreadZones:
+					goals = append(goals, fmt.Sprintf("\\+ same_zone(%s, %s)", nodeTerms[r[i].Id], nodeTerms[r[j].Id]))
+				}
+			}
+		}
+	}
+
+	return goals
+}
+
+// Returns Problog goals that place services on the same node or on distinct nodes according to affinity rules
+func getPlAffinityGoals(application *model.Application, nodeTerms map[string]string) []string {
+	goals := make([]string, 0)
+
+	for _, r := range application.Affinities {
+		services := r.GetServices()
+
+		if r.IsCoLocation() {
+			for _, s := range services[1:] {
+				goals = append(goals, fmt.Sprintf("%s = %s", nodeTerms[services[0]], nodeTerms[s]))
+			}
+			continue
+		}
+
+		for i := 0; i < len(services); i++ {
+			for j := i + 1; j < len(services); j++ {
+				goals = append(goals, fmt.Sprintf("%s \\= %s", nodeTerms[services[i]], nodeTerms[services[j]]))
+			}
+		}
+	}
+
+	return goals
+}
+
 // Calls Problog using the command string passed
 // It returns the output of the process
 func callProblog(code string) (string, error) {
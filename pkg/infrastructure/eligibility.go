@@ -0,0 +1,136 @@
+/*
+ * FogLute
+ *
+ * A Microservice Fog Orchestration platform.
+ *
+ * API version: 1.0.0
+ * Contact: andrea.liut@gmail.com
+ */
+package infrastructure
+
+import (
+	"fmt"
+	apiv1 "k8s.io/api/core/v1"
+	"strings"
+)
+
+// Node conditions that prevent a node from running new services when their status is True
+var pressureConditions = []apiv1.NodeConditionType{
+	apiv1.NodeMemoryPressure,
+	apiv1.NodeDiskPressure,
+	apiv1.NodePIDPressure,
+	apiv1.NodeNetworkUnavailable,
+}
+
+// A NodeEligibility is the result of the evaluation of a node for running services.
+type NodeEligibility struct {
+	// True if the node reports Ready status
+	Ready bool
+
+	// True if the node has been cordoned
+	Cordoned bool
+
+	// Pressure conditions reported by the node
+	Pressure []apiv1.NodeConditionType
+
+	// NoSchedule and NoExecute taints: only services that tolerate them can run on the node
+	Taints []apiv1.Taint
+}
+
+// Returns true if the node can run services that tolerate its taints
+func (e NodeEligibility) Usable() bool {
+	return e.Ready && !e.Cordoned && len(e.Pressure) == 0
+}
+
+func (e NodeEligibility) String() string {
+	reasons := make([]string, 0)
+
+	if !e.Ready {
+		reasons = append(reasons, "not ready")
+	}
+
+	if e.Cordoned {
+		reasons = append(reasons, "cordoned")
+	}
+
+	for _, c := range e.Pressure {
+		reasons = append(reasons, string(c))
+	}
+
+	for _, t := range e.Taints {
+		reasons = append(reasons, fmt.Sprintf("tainted %s", t.ToString()))
+	}
+
+	if len(reasons) == 0 {
+		return "eligible"
+	}
+
+	return strings.Join(reasons, ", ")
+}
+
+// Evaluates whether a node can be used for running services.
+func EvaluateNode(node *apiv1.Node) NodeEligibility {
+	e := NodeEligibility{
+		Ready:    isNodeReady(node),
+		Cordoned: node.Spec.Unschedulable,
+		Pressure: make([]apiv1.NodeConditionType, 0),
+		Taints:   GetBlockingTaints(node),
+	}
+
+	for _, cond := range node.Status.Conditions {
+		for _, p := range pressureConditions {
+			if cond.Type == p && cond.Status == apiv1.ConditionTrue {
+				e.Pressure = append(e.Pressure, p)
+			}
+		}
+	}
+
+	return e
+}
+
+// Returns the taints of a node that must be tolerated by the services that run on it
+func GetBlockingTaints(node *apiv1.Node) []apiv1.Taint {
+	taints := make([]apiv1.Taint, 0)
+	for _, t := range node.Spec.Taints {
+		if t.Effect == apiv1.TaintEffectNoSchedule || t.Effect == apiv1.TaintEffectNoExecute {
+			taints = append(taints, t)
+		}
+	}
+
+	return taints
+}
+
+// Returns true if the node is notifying Ready status.
+func isNodeReady(node *apiv1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == apiv1.NodeReady {
+			return cond.Status == apiv1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// Returns true if the node can be used for running services.
+// Tainted nodes are available only to services that tolerate their taints.
+func isNodeAvailableForScheduling(node *apiv1.Node) bool {
+	return EvaluateNode(node).Usable()
+}
+
+// Returns true if the blocking taints of two versions of a node are different
+func taintsChanged(oldNode, newNode *apiv1.Node) bool {
+	oldTaints := GetBlockingTaints(oldNode)
+	newTaints := GetBlockingTaints(newNode)
+
+	if len(oldTaints) != len(newTaints) {
+		return true
+	}
+
+	for i := range oldTaints {
+		if !oldTaints[i].MatchTaint(&newTaints[i]) || oldTaints[i].Value != newTaints[i].Value {
+			return true
+		}
+	}
+
+	return false
+}
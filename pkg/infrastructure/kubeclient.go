@@ -9,6 +9,7 @@
 package infrastructure
 
 import (
+	"fmt"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -29,3 +30,44 @@ func GetClientSet(path string) (*kubernetes.Clientset, error) {
 
 	return kubernetes.NewForConfig(config)
 }
+
+// A Cluster is a Kubernetes cluster managed by FogLute
+type Cluster struct {
+	// Name of the kubeconfig context of the cluster. It is empty if FogLute manages a single cluster
+	Name string
+
+	Clientset *kubernetes.Clientset
+}
+
+// Returns the clusters of the given contexts of a kubeconfig file.
+// If no context is given, it returns the cluster of the current context, or the one FogLute runs in.
+func GetClusters(path string, contexts []string) ([]Cluster, error) {
+	if len(contexts) == 0 {
+		clientset, err := GetClientSet(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Cluster{{Clientset: clientset}}, nil
+	}
+
+	clusters := make([]Cluster, 0, len(contexts))
+	for _, context := range contexts {
+		config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: path},
+			&clientcmd.ConfigOverrides{CurrentContext: context},
+		).ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("cannot load context %s: %s", context, err)
+		}
+
+		clientset, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return nil, fmt.Errorf("cannot connect to context %s: %s", context, err)
+		}
+
+		clusters = append(clusters, Cluster{Name: context, Clientset: clientset})
+	}
+
+	return clusters, nil
+}
@@ -9,172 +9,265 @@
 package infrastructure
 
 import (
+	"fmt"
+	"foglute/pkg/config"
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	listersv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"log"
+	"strings"
 	"sync"
 	"time"
 )
 
 const (
-	// update ticker delay
-	updateDelay = 2 * time.Minute
+	// informer resync period
+	resyncPeriod = 2 * time.Minute
+
+	// size of the buffer of subscription channels
+	subscriptionBufferSize = 64
+)
+
+// The type of a NodeEvent
+type NodeEventType int
+
+const (
+	NodeAdded NodeEventType = iota
+	NodeUpdated
+	NodeRemoved
 )
 
+func (t NodeEventType) String() string {
+	switch t {
+	case NodeAdded:
+		return "added"
+	case NodeUpdated:
+		return "updated"
+	case NodeRemoved:
+		return "removed"
+	}
+
+	return fmt.Sprintf("unknown(%d)", int(t))
+}
+
+// A NodeEvent notifies a change of a node of the cluster.
+type NodeEvent struct {
+	Type NodeEventType
+	Node *apiv1.Node
+
+	// True if the node can be used for running services
+	Schedulable bool
+
+	// True if the schedulability of the node changed (NodeUpdated only)
+	SchedulabilityChanged bool
+
+	// True if the FogLute capability labels of the node changed (NodeUpdated only)
+	LabelsChanged bool
+
+	// True if the NoSchedule and NoExecute taints of the node changed (NodeUpdated only)
+	TaintsChanged bool
+}
+
 // A NodeWatcher listen for changes of the infrastructure - the nodes of the Kubernetes cluster - and stores them
 // to let the application get the infrastructure faster.
 type NodeWatcher struct {
 	clientset *kubernetes.Clientset
 
-	// Mutex on node list
-	nodelistMutex *sync.Mutex
+	// Shared informer cache on nodes
+	informerFactory informers.SharedInformerFactory
+	lister          listersv1.NodeLister
 
-	// List of nodes
-	nodelist []apiv1.Node
+	// Mutex on subscribers
+	subscribersMutex *sync.Mutex
 
-	// Ticker for nodes update
-	updateTicker *time.Ticker
+	// Subscribers to node events
+	subscribers map[int]chan NodeEvent
+	nextID      int
 
 	// Stop channel
 	stop chan struct{}
 }
 
 // Handles the addition of a node
-func (nw *NodeWatcher) addFunc(node interface{}) {
-	n := node.(*apiv1.Node)
+func (nw *NodeWatcher) addFunc(obj interface{}) {
+	n := obj.(*apiv1.Node)
 	log.Printf("A node has been added: %s\n", n.Name)
 
 	// check if it can be used for task scheduling
-	if !isNodeAvailableForScheduling(n) {
-		log.Printf("Cannot use %s for scheduling tasks\n", n.Name)
-		return
+	eligibility := EvaluateNode(n)
+	schedulable := eligibility.Usable()
+	if !schedulable {
+		log.Printf("Cannot use %s for scheduling tasks: %s\n", n.Name, eligibility)
 	}
 
-	nw.nodelistMutex.Lock()
-	nw.nodelist = append(nw.nodelist, *n)
-	nw.nodelistMutex.Unlock()
+	nw.publish(NodeEvent{
+		Type:        NodeAdded,
+		Node:        n,
+		Schedulable: schedulable,
+	})
 }
 
-// Returns true if the node can be used for running services.
-func isNodeAvailableForScheduling(node *apiv1.Node) bool {
-	// Check readiness
-	if !isNodeReady(node) {
-		return false
+// Handles the update of a node.
+// Only changes of schedulability, taints and capability labels are notified.
+func (nw *NodeWatcher) updateFunc(oldObj, newObj interface{}) {
+	oldNode := oldObj.(*apiv1.Node)
+	newNode := newObj.(*apiv1.Node)
+
+	wasSchedulable := isNodeAvailableForScheduling(oldNode)
+	eligibility := EvaluateNode(newNode)
+	schedulable := eligibility.Usable()
+
+	event := NodeEvent{
+		Type:                  NodeUpdated,
+		Node:                  newNode,
+		Schedulable:           schedulable,
+		SchedulabilityChanged: wasSchedulable != schedulable,
+		LabelsChanged:         capabilityLabelsChanged(oldNode, newNode),
+		TaintsChanged:         taintsChanged(oldNode, newNode),
 	}
 
-	for _, t := range node.Spec.Taints {
-		if t.Effect == apiv1.TaintEffectNoSchedule {
-			return false
+	if !event.SchedulabilityChanged && !event.LabelsChanged && !event.TaintsChanged {
+		return
+	}
+
+	if event.SchedulabilityChanged {
+		if schedulable {
+			log.Printf("Node %s is now available for scheduling\n", newNode.Name)
+		} else {
+			log.Printf("Node %s is no longer available for scheduling: %s\n", newNode.Name, eligibility)
 		}
 	}
 
-	return true
-}
+	if event.TaintsChanged {
+		log.Printf("Taints of node %s changed\n", newNode.Name)
+	}
 
-// Returns true if the node is notifying Ready status.
-func isNodeReady(node *apiv1.Node) bool {
-	for _, cond := range node.Status.Conditions {
-		if cond.Type == apiv1.NodeReady {
-			return true
-		}
+	if event.LabelsChanged {
+		log.Printf("Capabilities of node %s changed\n", newNode.Name)
 	}
 
-	return false
+	nw.publish(event)
 }
 
 // Handles the deletion of a node
-func (nw *NodeWatcher) deleteFunc(node interface{}) {
-	removedNode := node.(*apiv1.Node)
-	log.Printf("A node has been removed: %s\n", removedNode.Name)
-
-	nw.nodelistMutex.Lock()
-	defer nw.nodelistMutex.Unlock()
-	for i, n := range nw.nodelist {
-		if n.UID == removedNode.UID {
-			// Remove the node from the list
-			nw.nodelist = append(nw.nodelist[:i], nw.nodelist[i+1:]...)
+func (nw *NodeWatcher) deleteFunc(obj interface{}) {
+	removedNode, ok := obj.(*apiv1.Node)
+	if !ok {
+		// The final state of the node is unknown if the watch missed the deletion
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			log.Printf("Warning: unexpected object removed: %v\n", obj)
+			return
+		}
 
-			log.Printf("Node (%s) %s removed from available nodes\n", n.UID, n.Name)
+		if removedNode, ok = tombstone.Obj.(*apiv1.Node); !ok {
+			log.Printf("Warning: unexpected object removed: %v\n", tombstone.Obj)
 			return
 		}
 	}
 
-	log.Println("Warning: removed node not found in previous list")
-}
-
-// Fetch all nodes from the cluster
-func (nw *NodeWatcher) fetchNodes() ([]apiv1.Node, error) {
-	list, err := nw.clientset.CoreV1().Nodes().List(metav1.ListOptions{})
-	if err != nil {
-		return nil, err
-	}
+	log.Printf("A node has been removed: %s\n", removedNode.Name)
 
-	return list.Items, nil
+	nw.publish(NodeEvent{
+		Type:        NodeRemoved,
+		Node:        removedNode,
+		Schedulable: false,
+	})
 }
 
-// Starts the node watcher
-func (nw *NodeWatcher) startWatching() {
-	watchlist := cache.NewListWatchFromClient(nw.clientset.CoreV1().RESTClient(), "nodes", apiv1.NamespaceAll, fields.Everything())
-
-	_, controller := cache.NewInformer(
-		watchlist,
-		&apiv1.Node{},
-		time.Second*30,
-		cache.ResourceEventHandlerFuncs{
-			AddFunc:    nw.addFunc,
-			DeleteFunc: nw.deleteFunc,
-		})
+// Returns true if the labels that describe node capabilities are different between two versions of the node
+func capabilityLabelsChanged(oldNode, newNode *apiv1.Node) bool {
+	prefix := config.FoglutePackageName + "/"
 
-	go controller.Run(nw.stop)
+	isCapabilityLabel := func(name string) bool {
+		return strings.HasPrefix(name, prefix) || name == apiv1.LabelZoneFailureDomain
+	}
 
-	// Update the list from time to time
-	nw.updateTicker = time.NewTicker(updateDelay)
+	for name, value := range oldNode.Labels {
+		if isCapabilityLabel(name) && newNode.Labels[name] != value {
+			return true
+		}
+	}
+
+	for name := range newNode.Labels {
+		if _, exists := oldNode.Labels[name]; isCapabilityLabel(name) && !exists {
+			return true
+		}
+	}
 
-	go nw.nodeUpdater()
+	return false
 }
 
-// Updates the list of nodes periodically
-func (nw *NodeWatcher) nodeUpdater() {
-	log.Println("Node Updater started!")
-	for {
-		select {
-		case <-nw.updateTicker.C:
-			go nw.updateNodes()
-		case <-nw.stop:
-			nw.updateTicker.Stop()
+// Sends an event to all subscribers.
+// Events are dropped for subscribers that are not keeping up.
+func (nw *NodeWatcher) publish(event NodeEvent) {
+	nw.subscribersMutex.Lock()
+	defer nw.subscribersMutex.Unlock()
 
-			log.Println("Node Updater stopped!")
-			return
+	for id, ch := range nw.subscribers {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("Warning: subscriber %d is too slow, node event %s for %s dropped\n", id, event.Type, event.Node.Name)
 		}
 	}
 }
 
-// Updates the list of nodes fetching nodes from the cluster
-func (nw *NodeWatcher) updateNodes() {
-	newNodes, err := nw.fetchNodes()
-	if err != nil {
-		log.Printf("Cannot retrieve nodes: %s\n", err)
+// Subscribes to node events.
+// It returns the channel on which events are delivered and a function to cancel the subscription.
+func (nw *NodeWatcher) Subscribe() (<-chan NodeEvent, func()) {
+	nw.subscribersMutex.Lock()
+	defer nw.subscribersMutex.Unlock()
+
+	id := nw.nextID
+	nw.nextID++
+
+	ch := make(chan NodeEvent, subscriptionBufferSize)
+	nw.subscribers[id] = ch
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			nw.subscribersMutex.Lock()
+			defer nw.subscribersMutex.Unlock()
+
+			delete(nw.subscribers, id)
+			close(ch)
+		})
 	}
 
-	nw.nodelistMutex.Lock()
-	defer nw.nodelistMutex.Unlock()
+	return ch, cancel
+}
 
-	oldNodeCount := len(nw.nodelist)
+// Starts the node watcher and waits for the node cache to be filled
+func (nw *NodeWatcher) startWatching() error {
+	nodeInformer := nw.informerFactory.Core().V1().Nodes()
 
-	nw.nodelist = make([]apiv1.Node, 0)
+	nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    nw.addFunc,
+		UpdateFunc: nw.updateFunc,
+		DeleteFunc: nw.deleteFunc,
+	})
 
-	for _, node := range newNodes {
-		if isNodeAvailableForScheduling(&node) {
-			nw.nodelist = append(nw.nodelist, node)
-		} else {
-			log.Printf("Cannot use (%s) %s for job scheduling\n", node.UID, node.Name)
+	nw.lister = nodeInformer.Lister()
+
+	nw.informerFactory.Start(nw.stop)
+
+	log.Println("Waiting for node cache to sync...")
+
+	for informerType, synced := range nw.informerFactory.WaitForCacheSync(nw.stop) {
+		if !synced {
+			return fmt.Errorf("cannot sync cache of %v", informerType)
 		}
 	}
 
-	log.Printf("Node list updated. Old node count: %d, now %d\n", oldNodeCount, len(nw.nodelist))
+	log.Println("Node cache synced!")
+
+	return nil
 }
 
 // Stops the watcher
@@ -182,24 +275,48 @@ func (nw *NodeWatcher) Stop() {
 	close(nw.stop)
 }
 
-// Returns the list of nodes
+// Returns the list of nodes that can be used for running services.
+// Tainted nodes are included: services must tolerate their taints.
 func (nw *NodeWatcher) GetNodes() []apiv1.Node {
-	nw.nodelistMutex.Lock()
-	defer nw.nodelistMutex.Unlock()
+	nodes, err := nw.lister.List(labels.Everything())
+	if err != nil {
+		log.Printf("Cannot list nodes: %s\n", err)
+		return make([]apiv1.Node, 0)
+	}
 
-	return nw.nodelist
+	list := make([]apiv1.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if isNodeAvailableForScheduling(node) {
+			list = append(list, *node.DeepCopy())
+		}
+	}
+
+	return list
 }
 
-func NewNodeWatcher(clientset *kubernetes.Clientset) (*NodeWatcher, error) {
+// Returns a new NodeWatcher on the nodes matching a label selector.
+// An empty selector watches all the nodes of the cluster.
+func NewNodeWatcher(clientset *kubernetes.Clientset, nodeSelector string) (*NodeWatcher, error) {
+	if _, err := labels.Parse(nodeSelector); err != nil {
+		return nil, fmt.Errorf("invalid node selector %s: %s", nodeSelector, err)
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, resyncPeriod,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = nodeSelector
+		}))
+
 	nw := &NodeWatcher{
-		clientset:     clientset,
-		nodelistMutex: &sync.Mutex{},
-		nodelist:      make([]apiv1.Node, 0),
-		stop:          make(chan struct{}),
-		updateTicker:  nil,
+		clientset:        clientset,
+		informerFactory:  factory,
+		subscribersMutex: &sync.Mutex{},
+		subscribers:      make(map[int]chan NodeEvent),
+		stop:             make(chan struct{}),
 	}
 
-	nw.startWatching()
+	if err := nw.startWatching(); err != nil {
+		return nil, err
+	}
 
 	return nw, nil
 }
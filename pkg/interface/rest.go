@@ -45,7 +45,7 @@ func newResponse(message string, errorMessage string) *Response {
 // Handles error responses
 func handleError(w http.ResponseWriter, status int, message string, args ...interface{}) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	r := newResponse("", fmt.Sprintf(message, args))
+	r := newResponse("", fmt.Sprintf(message, args...))
 	j, _ := json.Marshal(r)
 	http.Error(w, string(j), status)
 }
@@ -58,7 +58,12 @@ func applicationsHandler(manager *deployment.Manager, w http.ResponseWriter, r *
 		// Returns all active deployments
 		deployments := manager.GetDeployments()
 
-		err := json.NewEncoder(w).Encode(deployments)
+		redacted := make([]*deployment.Deploy, len(deployments))
+		for i, d := range deployments {
+			redacted[i] = d.Redacted()
+		}
+
+		err := json.NewEncoder(w).Encode(redacted)
 		if err != nil {
 			log.Println(err)
 		}
@@ -72,6 +77,24 @@ func applicationsHandler(manager *deployment.Manager, w http.ResponseWriter, r *
 			return
 		}
 
+		if err := app.Validate(); err != nil {
+			handleError(w, http.StatusBadRequest, "Invalid application: %s", err)
+			return
+		}
+
+		if err := manager.CheckSecurityPolicy(&app); err != nil {
+			handleError(w, http.StatusForbidden, "Application %s %s", app.ID, err)
+			return
+		}
+
+		// Send the NetworkPolicies of the application without deploying it
+		if r.URL.Query().Get("preview") == "network_policies" {
+			if err = json.NewEncoder(w).Encode(manager.PreviewNetworkPolicies(&app)); err != nil {
+				log.Println(err)
+			}
+			return
+		}
+
 		go func() {
 			// Add the application to the manager
 			addErrors := manager.AddApplication(&app)
@@ -108,7 +131,7 @@ func applicationHandler(manager *deployment.Manager, w http.ResponseWriter, r *h
 	switch r.Method {
 	case http.MethodGet:
 		// Send the application
-		err := json.NewEncoder(w).Encode(deploy)
+		err := json.NewEncoder(w).Encode(deploy.Redacted())
 		if err != nil {
 			log.Println(err)
 		}